@@ -0,0 +1,129 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+type (
+	// AdmissionRequest describes the request being admitted by a Hook; including who made it, the operation and whether it is a dry-run.
+	//
+	// It is obtained from the ctx passed to the Hook funcs with AdmissionRequestFrom
+	AdmissionRequest struct {
+		// UID identifies the individual request
+		UID string
+		// Operation is the operation being performed; one of "CREATE", "UPDATE", "DELETE" or "CONNECT"
+		Operation string
+		// Namespace and Name identify the resource, where known. The Name is empty where it is generated by the API server on creation
+		Namespace, Name string
+		// Subresource is the subresource being operated on, if any; for example "status" or "scale"
+		Subresource string
+		// User is the name of the authenticated user that made the request; for a service account, of the form `system:serviceaccount:<namespace>:<name>`
+		User string
+		// Groups are the groups of the authenticated user that made the request
+		Groups []string
+		// DryRun is true if the request will not be persisted, such as for `kubectl apply --dry-run=server`
+		DryRun bool
+		// Options is the options of the operation, such as a metav1.CreateOptions, metav1.UpdateOptions or metav1.DeleteOptions, as raw JSON
+		Options runtime.RawExtension
+
+		resource schema.GroupResource
+	}
+)
+
+const (
+	serviceAccountUserPrefix = "system:serviceaccount:"
+)
+
+// AdmissionRequestFrom returns the AdmissionRequest being admitted, from the ctx passed to a Hook func. False is returned if the ctx is not that of a Hook func
+func AdmissionRequestFrom(ctx context.Context) (AdmissionRequest, bool) {
+	req, err := admission.RequestFromContext(ctx)
+
+	if err != nil {
+		return AdmissionRequest{}, false
+	}
+
+	return AdmissionRequest{
+		UID:         string(req.UID),
+		Operation:   string(req.Operation),
+		Namespace:   req.Namespace,
+		Name:        req.Name,
+		Subresource: req.SubResource,
+		User:        req.UserInfo.Username,
+		Groups:      req.UserInfo.Groups,
+		DryRun:      req.DryRun != nil && *req.DryRun,
+		Options:     req.Options,
+		resource:    schema.GroupResource{Group: req.Resource.Group, Resource: req.Resource.Resource},
+	}, true
+}
+
+// InGroup returns true if the user that made the request is a member of the specified group
+func (r AdmissionRequest) InGroup(group string) bool {
+	return slices.Contains(r.Groups, group)
+}
+
+// ServiceAccount returns the namespace and name of the service account that made the request. False is returned if it was not made by a service account
+func (r AdmissionRequest) ServiceAccount() (namespace, name string, ok bool) {
+	serviceAccount, ok := strings.CutPrefix(r.User, serviceAccountUserPrefix)
+
+	if !ok {
+		return "", "", false
+	}
+
+	namespace, name, ok = strings.Cut(serviceAccount, ":")
+
+	return namespace, name, ok && namespace != "" && name != ""
+}
+
+// IsServiceAccount returns true if the request was made by one of the specified service accounts, each defined as `<namespace>/<name>`.
+// A name of `*` matches any service account in the namespace; for example `kube-system/*`
+func (r AdmissionRequest) IsServiceAccount(serviceAccounts ...string) bool {
+	namespace, name, ok := r.ServiceAccount()
+
+	if !ok {
+		return false
+	}
+
+	return slices.ContainsFunc(serviceAccounts, func(serviceAccount string) bool {
+		return serviceAccount == namespace+"/"+name || serviceAccount == namespace+"/*"
+	})
+}
+
+// AllowServiceAccounts returns a Forbidden error, for return by a Hook func, unless the request being admitted was made by one of the specified service
+// accounts, each defined as `<namespace>/<name>` or `<namespace>/*`. Requests whose identity cannot be determined are forbidden
+func AllowServiceAccounts(ctx context.Context, serviceAccounts ...string) error {
+	req, ok := AdmissionRequestFrom(ctx)
+
+	if !ok {
+		return apierrors.NewForbidden(req.resource, "", fmt.Errorf("the requesting identity could not be determined"))
+	}
+
+	if !req.IsServiceAccount(serviceAccounts...) {
+		return apierrors.NewForbidden(req.resource, req.Name, fmt.Errorf("user %v is not one of the permitted service accounts %v", req.User, serviceAccounts))
+	}
+
+	return nil
+}
+
+// DenyServiceAccounts returns a Forbidden error, for return by a Hook func, if the request being admitted was made by any of the specified service
+// accounts, each defined as `<namespace>/<name>` or `<namespace>/*`. Requests whose identity cannot be determined are forbidden
+func DenyServiceAccounts(ctx context.Context, serviceAccounts ...string) error {
+	req, ok := AdmissionRequestFrom(ctx)
+
+	if !ok {
+		return apierrors.NewForbidden(req.resource, "", fmt.Errorf("the requesting identity could not be determined"))
+	}
+
+	if req.IsServiceAccount(serviceAccounts...) {
+		return apierrors.NewForbidden(req.resource, req.Name, fmt.Errorf("user %v is one of the denied service accounts %v", req.User, serviceAccounts))
+	}
+
+	return nil
+}
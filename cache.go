@@ -0,0 +1,178 @@
+package kapi
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// CacheConfig defines how resources of a specific type are cached by a kapi.Cluster.
+	//
+	// It is used to reduce the memory consumed by the cache, by limiting which resources are cached and what data is retained for them
+	CacheConfig struct {
+		// LabelSelector limits the cache to resources that match the label selector; for example "app=example,tier in (web, api)"
+		LabelSelector string
+		// FieldSelector limits the cache to resources that match the field selector; for example "status.phase=Running".
+		// Only fields supported by the k8s API server for the resource type can be used
+		FieldSelector string
+		// Namespaces overrides the ClusterConfig.Namespaces for the resource type. Where unset, the ClusterConfig.Namespaces are used.
+		// It must not be set for cluster-scoped resource types
+		Namespaces []string
+		// StripManagedFields removes the managedFields metadata from resources before they are cached
+		StripManagedFields bool
+		// StripAnnotations removes the specified annotations from resources before they are cached
+		StripAnnotations []string
+		// Transform, where set, is invoked with each resource before it is cached and returns the resource to cache.
+		// It is invoked after any StripManagedFields and StripAnnotations processing
+		Transform func(resource client.Object) (client.Object, error)
+	}
+	// cacheScope defines which resources of a type the cache can hold
+	cacheScope struct {
+		namespaces map[string]cache.Config
+		label      labels.Selector
+		field      fields.Selector
+	}
+)
+
+// byObject returns the controller-runtime cache configuration equivalent to the passed CacheConfigs
+func byObject(cacheConfigs map[KindType]CacheConfig, crds []CRDs) (map[client.Object]cache.ByObject, error) {
+	byObject := make(map[client.Object]cache.ByObject, len(cacheConfigs))
+
+	for kindType, cacheConfig := range maps.All(cacheConfigs) {
+		obj, ok := kindType.(client.Object)
+
+		if !ok {
+			return nil, fmt.Errorf("cache config key of type %T is not a k8s resource type", kindType)
+		}
+
+		cfg := cache.ByObject{}
+
+		if cacheConfig.LabelSelector != "" {
+			selector, err := labels.Parse(cacheConfig.LabelSelector)
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse cache config label selector %q for %T. %v", cacheConfig.LabelSelector, kindType, err)
+			}
+
+			cfg.Label = selector
+		}
+
+		if cacheConfig.FieldSelector != "" {
+			selector, err := fields.ParseSelector(cacheConfig.FieldSelector)
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse cache config field selector %q for %T. %v", cacheConfig.FieldSelector, kindType, err)
+			}
+
+			cfg.Field = selector
+		}
+
+		if len(cacheConfig.Namespaces) > 0 {
+			if isClusterScoped(crds, kindType) {
+				return nil, fmt.Errorf("cache config for cluster-scoped %T must not set namespaces", kindType)
+			}
+
+			cfg.Namespaces = make(map[string]cache.Config, len(cacheConfig.Namespaces))
+
+			for ns := range slices.Values(cacheConfig.Namespaces) {
+				cfg.Namespaces[ns] = cache.Config{}
+			}
+		}
+
+		if cacheConfig.StripManagedFields || len(cacheConfig.StripAnnotations) > 0 || cacheConfig.Transform != nil {
+			cfg.Transform = cacheConfig.transform
+		}
+
+		byObject[obj] = cfg
+	}
+
+	return byObject, nil
+}
+
+// newCacheScopes returns the cacheScope of each resource type with a cache config, keyed by type. Other types are held in the default namespaces
+func newCacheScopes(defaultNamespaces map[string]cache.Config, byObject map[client.Object]cache.ByObject) (cacheScope, map[reflect.Type]cacheScope) {
+	scopes := make(map[reflect.Type]cacheScope, len(byObject))
+
+	for obj, cfg := range maps.All(byObject) {
+		scope := cacheScope{namespaces: defaultNamespaces, label: cfg.Label, field: cfg.Field}
+
+		if len(cfg.Namespaces) > 0 {
+			scope.namespaces = cfg.Namespaces
+		}
+
+		scopes[reflect.TypeOf(obj)] = scope
+	}
+
+	return cacheScope{namespaces: defaultNamespaces}, scopes
+}
+
+// holds returns false if the resource is outside of the namespaces, or does not match the selectors, of the cacheScope; so can never be cached
+func (s cacheScope) holds(resource client.Object) bool {
+	if namespace := resource.GetNamespace(); namespace != "" && len(s.namespaces) > 0 {
+		if _, ok := s.namespaces[namespace]; !ok {
+			return false
+		}
+	}
+
+	if s.label != nil && !s.label.Matches(labels.Set(resource.GetLabels())) {
+		return false
+	}
+
+	if s.field == nil || s.field.Empty() {
+		return true
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(resource)
+
+	if err != nil {
+		return true // where the fields cannot be read, the resource is assumed to be cacheable
+	}
+
+	set := fields.Set{}
+
+	for requirement := range slices.Values(s.field.Requirements()) {
+		if value, found, err := unstructured.NestedFieldNoCopy(content, strings.Split(requirement.Field, ".")...); found && err == nil {
+			set[requirement.Field] = fmt.Sprint(value)
+		}
+	}
+
+	return s.field.Matches(set)
+}
+
+func (c CacheConfig) transform(in any) (any, error) {
+	resource, ok := in.(client.Object)
+
+	if !ok {
+		// tombstones of deleted resources are passed as-is
+		return in, nil
+	}
+
+	if c.StripManagedFields {
+		resource.SetManagedFields(nil)
+	}
+
+	if len(c.StripAnnotations) > 0 {
+		if annotations := resource.GetAnnotations(); annotations != nil {
+			for annotation := range slices.Values(c.StripAnnotations) {
+				delete(annotations, annotation)
+			}
+			resource.SetAnnotations(annotations)
+		}
+	}
+
+	if c.Transform == nil {
+		return resource, nil
+	}
+
+	return c.Transform(resource)
+}
@@ -0,0 +1,370 @@
+package kapi
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// CELRule defines a CEL validation rule, as included in the `x-kubernetes-validations` of a generated CustomResourceDefinition.
+	//
+	// A rule is evaluated with the value it is declared on as `self`. On updates, the previous value is available as `oldSelf`; rules that
+	// reference `oldSelf`, known as transition rules, are only evaluated where a previous value exists
+	CELRule struct {
+		// Rule defines the CEL expression, which must evaluate to true for the value to be valid; for example `self.minReplicas <= self.maxReplicas`
+		Rule string
+		// Message optionally defines the error reported when the rule is not satisfied. By default, this is `failed rule: <Rule>`
+		Message string
+		// FieldPath optionally defines the path, relative to the value the rule is declared on, of the field reported when the rule is not satisfied; for example `.maxReplicas`
+		FieldPath string
+	}
+	// CELValidator is implemented by types that declare CEL validation rules which apply to the type as a whole; for example, rules that compare its fields.
+	// The rules of individual fields can instead be declared with a `cel` tag, alongside an optional `celMessage` tag; or, for immutable fields,
+	// with a `kapi:"immutable"` tag
+	CELValidator interface {
+		CELRules() []CELRule
+	}
+	celProgram struct {
+		program       cel.Program
+		transitionary bool
+	}
+	celProgramResult struct {
+		celProgram celProgram
+		err        error
+	}
+)
+
+const (
+	// celCostLimit is the limit on the cost of evaluating a single rule, as applied by the api server
+	celCostLimit = 1000000
+)
+
+var (
+	celValidatorType = reflect.TypeOf((*CELValidator)(nil)).Elem()
+	celPrograms      sync.Map // map[string]celProgramResult
+	celEnv           = sync.OnceValues(func() (*cel.Env, error) {
+		return cel.NewEnv(
+			cel.Variable("self", cel.DynType),
+			cel.Variable("oldSelf", cel.DynType),
+			cel.CrossTypeNumericComparisons(true),
+			cel.OptionalTypes(),
+			ext.Strings(ext.StringsVersion(2)),
+			ext.Sets(),
+			ext.Lists(),
+		)
+	})
+)
+
+// ValidateCEL evaluates the CEL validation rules declared on the type of the passed resource, and the types of its fields, as they would be
+// by the api server and by any hook registered for the type. It returns the rules that were not satisfied, as field errors.
+//
+// oldResource may be nil, in which case the resource is validated as if it were being created and any transition rules are not evaluated.
+//
+// ValidateCEL requires no cluster, so is typically used to unit test rules
+func ValidateCEL[T client.Object](oldResource, resource T) (field.ErrorList, error) {
+	schema, err := schemaFor(reflect.TypeOf(resource))
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate schema for %T. %v", resource, err)
+	}
+
+	var old any
+
+	if !reflect.ValueOf(oldResource).IsNil() {
+		old = oldResource
+	}
+
+	return validateCELRules(&schema, old, resource)
+}
+
+// validateCELRules evaluates the CEL rules of the passed schema, and of the schemas it contains, against resource and, where not nil, oldResource
+func validateCELRules(schema *apiextensionsv1.JSONSchemaProps, oldResource, resource any) (field.ErrorList, error) {
+	self, err := runtime.DefaultUnstructuredConverter.ToUnstructured(resource)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %T to unstructured. %v", resource, err)
+	}
+
+	var oldSelf map[string]any
+
+	if oldResource != nil {
+		if oldSelf, err = runtime.DefaultUnstructuredConverter.ToUnstructured(oldResource); err != nil {
+			return nil, fmt.Errorf("unable to convert %T to unstructured. %v", oldResource, err)
+		}
+	}
+
+	errs := field.ErrorList{}
+
+	if err := evaluateCELRules(schema, nil, self, oldSelf, oldResource != nil, &errs); err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+func evaluateCELRules(schema *apiextensionsv1.JSONSchemaProps, path *field.Path, self, oldSelf any, hasOldSelf bool, errs *field.ErrorList) error {
+	for rule := range slices.Values(schema.XValidations) {
+		prg, err := compileCEL(rule.Rule)
+
+		if err != nil {
+			return err
+		}
+
+		if prg.transitionary && !hasOldSelf {
+			continue
+		}
+
+		vars := map[string]any{"self": self}
+
+		if hasOldSelf {
+			vars["oldSelf"] = oldSelf
+		}
+
+		rulePath := path
+
+		if rule.FieldPath != "" {
+			for name := range strings.SplitSeq(strings.TrimPrefix(rule.FieldPath, "."), ".") {
+				rulePath = rulePath.Child(name)
+			}
+		}
+
+		out, _, err := prg.program.Eval(vars)
+
+		switch {
+		case err != nil:
+			*errs = append(*errs, field.Invalid(rulePath, schema.Type, fmt.Sprintf("rule %q could not be evaluated. %v", rule.Rule, err)))
+		case out != types.True:
+			message := rule.Message
+
+			if message == "" {
+				message = fmt.Sprintf("failed rule: %v", rule.Rule)
+			}
+
+			*errs = append(*errs, field.Invalid(rulePath, schema.Type, message))
+		}
+	}
+
+	switch self := self.(type) {
+	case map[string]any:
+		oldSelf, _ := oldSelf.(map[string]any)
+
+		if len(schema.Properties) > 0 {
+			for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+				value, ok := self[name]
+
+				if !ok {
+					continue
+				}
+
+				oldValue, hasOldValue := oldSelf[name]
+				propertySchema := schema.Properties[name]
+
+				if err := evaluateCELRules(&propertySchema, path.Child(name), value, oldValue, hasOldSelf && hasOldValue, errs); err != nil {
+					return err
+				}
+			}
+		} else if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			for _, key := range slices.Sorted(maps.Keys(self)) {
+				oldValue, hasOldValue := oldSelf[key]
+
+				if err := evaluateCELRules(schema.AdditionalProperties.Schema, path.Key(key), self[key], oldValue, hasOldSelf && hasOldValue, errs); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		// as with the api server, list items are not correlated with their previous values, so transition rules are not evaluated for them
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, value := range self {
+				if err := evaluateCELRules(schema.Items.Schema, path.Index(i), value, nil, false, errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// compileCEL returns the cached celProgram for the passed rule, compiling it on first use
+func compileCEL(rule string) (celProgram, error) {
+	if result, ok := celPrograms.Load(rule); ok {
+		return result.(celProgramResult).celProgram, result.(celProgramResult).err
+	}
+
+	result := celProgramResult{}
+	result.celProgram, result.err = newCELProgram(rule)
+	celPrograms.Store(rule, result)
+
+	return result.celProgram, result.err
+}
+
+func newCELProgram(rule string) (celProgram, error) {
+	env, err := celEnv()
+
+	if err != nil {
+		return celProgram{}, fmt.Errorf("unable to create cel environment. %v", err)
+	}
+
+	ast, issues := env.Compile(rule)
+
+	if issues.Err() != nil {
+		return celProgram{}, fmt.Errorf("unable to compile cel rule %q. %v", rule, issues.Err())
+	}
+
+	if outputType := ast.OutputType(); outputType != cel.BoolType && outputType != cel.DynType {
+		return celProgram{}, fmt.Errorf("cel rule %q evaluates to %v rather than bool", rule, outputType)
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(celCostLimit))
+
+	if err != nil {
+		return celProgram{}, fmt.Errorf("unable to create program for cel rule %q. %v", rule, err)
+	}
+
+	transitionary := false
+
+	for ref := range maps.Values(ast.NativeRep().ReferenceMap()) {
+		if ref.Name == "oldSelf" {
+			transitionary = true
+		}
+	}
+
+	return celProgram{program: program, transitionary: transitionary}, nil
+}
+
+// compileCELRules compiles the CEL rules of the passed schema, and of the schemas it contains, returning the first error encountered
+func compileCELRules(schema *apiextensionsv1.JSONSchemaProps) error {
+	for rule := range slices.Values(schema.XValidations) {
+		if _, err := compileCEL(rule.Rule); err != nil {
+			return err
+		}
+	}
+
+	for property := range maps.Values(schema.Properties) {
+		if err := compileCELRules(&property); err != nil {
+			return err
+		}
+	}
+
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		if err := compileCELRules(schema.AdditionalProperties.Schema); err != nil {
+			return err
+		}
+	}
+
+	if schema.Items != nil && schema.Items.Schema != nil {
+		return compileCELRules(schema.Items.Schema)
+	}
+
+	return nil
+}
+
+// celTypeRules returns the CEL rules declared by the type t, where it implements CELValidator
+func celTypeRules(t reflect.Type) apiextensionsv1.ValidationRules {
+	ptrType := reflect.PointerTo(t)
+
+	if !ptrType.Implements(celValidatorType) {
+		return nil
+	}
+
+	return validationRules(reflect.New(t).Interface().(CELValidator).CELRules())
+}
+
+// celFieldRules returns the CEL rules declared by the `cel` and `celMessage` tags of the passed field
+func celFieldRules(field reflect.StructField) apiextensionsv1.ValidationRules {
+	rule, ok := field.Tag.Lookup("cel")
+
+	if !ok {
+		return nil
+	}
+
+	return validationRules([]CELRule{{Rule: rule, Message: field.Tag.Get("celMessage")}})
+}
+
+func validationRules(rules []CELRule) apiextensionsv1.ValidationRules {
+	validationRules := apiextensionsv1.ValidationRules{}
+
+	for rule := range slices.Values(rules) {
+		validationRules = append(validationRules, apiextensionsv1.ValidationRule{Rule: rule.Rule, Message: rule.Message, FieldPath: rule.FieldPath})
+	}
+
+	return validationRules
+}
+
+// declaresCELRules returns true if the type t, or any type it contains, declares CEL rules with a `cel` tag, an immutable field or by
+// implementing CELValidator. Unlike hasCELRules, it does not require a schema to be generated for the type
+func declaresCELRules(t reflect.Type) bool {
+	return declaresCELRulesOfType(t, map[reflect.Type]bool{})
+}
+
+func declaresCELRulesOfType(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || visited[t] || t == objectMetaType {
+		return false
+	}
+
+	visited[t] = true
+
+	if reflect.PointerTo(t).Implements(celValidatorType) {
+		return true
+	}
+
+	for field := range structFields(t) {
+		if _, ok := field.Tag.Lookup("cel"); ok {
+			return true
+		}
+
+		if rule, err := parseTagRule(field.Tag.Get("kapi")); err == nil && rule.immutable {
+			return true
+		}
+
+		if declaresCELRulesOfType(field.Type, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasCELRules returns true if the schema, or any schema it contains, declares CEL rules
+func hasCELRules(schema *apiextensionsv1.JSONSchemaProps) bool {
+	if schema == nil {
+		return false
+	}
+
+	if len(schema.XValidations) > 0 {
+		return true
+	}
+
+	if schema.Items != nil && hasCELRules(schema.Items.Schema) {
+		return true
+	}
+
+	if schema.AdditionalProperties != nil && hasCELRules(schema.AdditionalProperties.Schema) {
+		return true
+	}
+
+	for _, property := range schema.Properties {
+		if hasCELRules(&property) {
+			return true
+		}
+	}
+
+	return false
+}
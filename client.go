@@ -4,105 +4,390 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type (
 	// Client can be used to perform various IO operations against resources on a k8s cluster
 	Client[TItem client.Object, TList client.ObjectList] struct {
-		getClient        func() (client.Client, error)
-		resourceType     reflect.Type
-		resourceListType reflect.Type
+		cluster               *Cluster
+		uncachedClient        client.WithWatch
+		impersonate           rest.ImpersonationConfig
+		cached                bool
+		readYourWritesTimeout time.Duration
+		newResource           func() TItem
+		newResourceList       func() TList
+		resourceType          string
+		resourceListType      string
+		interceptors          []Interceptor
+		listErr               error
 	}
 	// Subresource represents a section of a resource that can be modified independently of the resource as a whole
 	Subresource string
+	// PatchType defines the format of the data passed to Patch
+	PatchType string
 )
 
 const (
 	SubresourceStatus Subresource = "status"
-	SubresourceScale  Subresource = "scale"
+	// SubresourceScale identifies the scale subresource. As its representation differs from that of the resource itself, it cannot be
+	// passed to Update or Patch; use GetScale and UpdateScale instead
+	SubresourceScale Subresource = "scale"
 )
 
-// Create creates a resource on the k8s cluster
-func (c *Client[TItem, TList]) Create(ctx context.Context, resource TItem) error {
-	defer c.observe(ctx, "create", resource)()
+const (
+	// PatchTypeMerge indicates the patch data is a JSON merge patch, as defined by RFC 7386
+	PatchTypeMerge = PatchType(types.MergePatchType)
+	// PatchTypeStrategicMerge indicates the patch data is a k8s strategic merge patch. This is only supported by built-in resource types
+	PatchTypeStrategicMerge = PatchType(types.StrategicMergePatchType)
+	// PatchTypeJSON indicates the patch data is a JSON patch, as defined by RFC 6902
+	PatchTypeJSON = PatchType(types.JSONPatchType)
+)
 
-	clt, err := c.getClient()
+// Create creates a resource on the k8s cluster.
+//
+// If DryRun is passed, the create is processed by the cluster but not persisted
+func (c *Client[TItem, TList]) Create(ctx context.Context, resource TItem, opts ...Option) error {
+	return c.intercept(ctx, ActionCreate, resource.GetNamespace(), resource.GetName(), resource, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	return clt.Create(ctx, resource)
+		o := newWriteOptions(ctx, opts)
+
+		if o.dryRun {
+			if err := clt.Create(ctx, resource, client.DryRunAll); err != nil {
+				return err
+			}
+
+			c.logDryRun(ctx, ActionCreate, resource.GetNamespace(), resource.GetName(), nil, resource)
+
+			return nil
+		}
+
+		if err := clt.Create(ctx, resource); err != nil {
+			return err
+		}
+
+		c.recordWrite(resource, false)
+
+		return nil
+	})
 }
 
 // Update modifies a resource on the k8s cluster.
-// Optionally, specific subresources can be provided, which will limit updates to only those subresources
-func (c *Client[TItem, TList]) Update(ctx context.Context, resource TItem, subresources ...Subresource) error {
-	defer c.observe(ctx, "update", resource)()
-	clt, err := c.getClient()
+// Optionally, specific subresources can be provided, which will limit updates to only those subresources.
+//
+// If DryRun is passed, the update is processed by the cluster but not persisted
+func (c *Client[TItem, TList]) Update(ctx context.Context, resource TItem, opts ...Option) error {
+	return c.intercept(ctx, ActionUpdate, resource.GetNamespace(), resource.GetName(), resource, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	if len(subresources) == 0 {
-		return clt.Update(ctx, resource)
-	}
+		o := newWriteOptions(ctx, opts)
+
+		var original runtime.Object
 
-	for _, subresource := range subresources {
-		if err = clt.SubResource(string(subresource)).Update(ctx, resource); err != nil {
-			return fmt.Errorf("unable to update subresource %v. %v", subresource, err)
+		if o.dryRun {
+			original = c.current(ctx, resource.GetNamespace(), resource.GetName())
 		}
-	}
 
-	return nil
+		if len(o.subresources) == 0 {
+			updateOpts := []client.UpdateOption{}
+
+			if o.dryRun {
+				updateOpts = append(updateOpts, client.DryRunAll)
+			}
+
+			if err := clt.Update(ctx, resource, updateOpts...); err != nil {
+				return err
+			}
+
+			c.recordOrLogWrite(ctx, ActionUpdate, o, original, resource)
+
+			return nil
+		}
+
+		for _, subresource := range o.subresources {
+			if subresource == SubresourceScale {
+				return fmt.Errorf("unable to update subresource %v. use kapi.client.update-scale", subresource)
+			}
+
+			subresourceUpdateOpts := []client.SubResourceUpdateOption{}
+
+			if o.dryRun {
+				subresourceUpdateOpts = append(subresourceUpdateOpts, client.DryRunAll)
+			}
+
+			if err = clt.SubResource(string(subresource)).Update(ctx, resource, subresourceUpdateOpts...); err != nil {
+				return fmt.Errorf("unable to update subresource %v. %w", subresource, err)
+			}
+
+			c.recordOrLogWrite(ctx, ActionUpdate, o, original, resource)
+		}
+
+		return nil
+	})
 }
 
-// Delete removes a resource from the k8s cluster
-func (c *Client[TItem, TList]) Delete(ctx context.Context, resource TItem) error {
-	defer c.observe(ctx, "delete", resource)()
+// Patch modifies a resource on the k8s cluster by applying the specified patch data, of the specified PatchType, to it.
+// The resource is updated to reflect the patched state.
+//
+// As with Update, specific subresources can be provided, which will limit the patch to only those subresources.
+//
+// If DryRun is passed, the patch is processed by the cluster but not persisted
+func (c *Client[TItem, TList]) Patch(ctx context.Context, resource TItem, patchType PatchType, data []byte, opts ...Option) error {
+	return c.intercept(ctx, ActionPatch, resource.GetNamespace(), resource.GetName(), resource, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
 
-	clt, err := c.getClient()
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return err
-	}
+		o := newWriteOptions(ctx, opts)
+		patch := client.RawPatch(types.PatchType(patchType), data)
+
+		var original runtime.Object
+
+		if o.dryRun {
+			original = c.current(ctx, resource.GetNamespace(), resource.GetName())
+		}
+
+		if len(o.subresources) == 0 {
+			patchOpts := []client.PatchOption{}
+
+			if o.dryRun {
+				patchOpts = append(patchOpts, client.DryRunAll)
+			}
+
+			if err := clt.Patch(ctx, resource, patch, patchOpts...); err != nil {
+				return err
+			}
+
+			c.recordOrLogWrite(ctx, ActionPatch, o, original, resource)
+
+			return nil
+		}
+
+		for _, subresource := range o.subresources {
+			if subresource == SubresourceScale {
+				return fmt.Errorf("unable to patch subresource %v. use kapi.client.update-scale", subresource)
+			}
+
+			subresourcePatchOpts := []client.SubResourcePatchOption{}
+
+			if o.dryRun {
+				subresourcePatchOpts = append(subresourcePatchOpts, client.DryRunAll)
+			}
 
-	return clt.Delete(ctx, resource)
+			if err = clt.SubResource(string(subresource)).Patch(ctx, resource, patch, subresourcePatchOpts...); err != nil {
+				return fmt.Errorf("unable to patch subresource %v. %w", subresource, err)
+			}
+
+			c.recordOrLogWrite(ctx, ActionPatch, o, original, resource)
+		}
+
+		return nil
+	})
 }
 
-// Get returns data describing the specified resource
-func (c *Client[TItem, TList]) Get(ctx context.Context, namespace, name string) (TItem, error) {
-	resource := reflect.New(c.resourceType).Interface().(TItem)
+// Delete removes a resource from the k8s cluster.
+//
+// Options can be provided to set the propagation policy, grace period and preconditions of the delete. If WithWait is passed,
+// Delete blocks until the resource, and with PropagationForeground its dependents, no longer exist.
+//
+// If DryRun is passed, the delete is processed by the cluster but not persisted and WithWait is ignored
+func (c *Client[TItem, TList]) Delete(ctx context.Context, resource TItem, opts ...Option) error {
+	return c.intercept(ctx, ActionDelete, resource.GetNamespace(), resource.GetName(), resource, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
+
+		if err != nil {
+			return err
+		}
 
-	defer c.observe(ctx, "get", resource)()
+		o := newWriteOptions(ctx, opts)
 
-	clt, err := c.getClient()
+		deleteOpts := []client.DeleteOption{}
 
-	if err != nil {
-		return resource, err
-	}
+		for _, deleteOpt := range o.deleteOptions() {
+			deleteOpts = append(deleteOpts, deleteOpt)
+		}
+
+		if err := clt.Delete(ctx, resource, deleteOpts...); err != nil {
+			return err
+		}
+
+		if o.dryRun {
+			c.logDryRun(ctx, ActionDelete, resource.GetNamespace(), resource.GetName(), resource, nil)
+			return nil
+		}
+
+		c.recordWrite(resource, true)
+
+		if !o.wait {
+			return nil
+		}
+
+		return c.WaitForDeletion(ctx, resource.GetNamespace(), resource.GetName())
+	})
+}
+
+// DeleteAllOf removes all resources of the type associated with the client that are in the specified namespace and match the
+// specified label selector; for example "app=example,tier in (web, api)". An empty selector matches all resources.
+//
+// The API server only deletes collections of namespaced resources within a single namespace, so a namespace is required for namespaced
+// kinds and an error is returned where it is empty. For cluster-scoped kinds, the namespace must be empty.
+//
+// The same options as Delete are supported. If WithWait is passed, DeleteAllOf blocks until all of the matched resources no longer exist.
+// If DryRun is passed, the delete is processed by the cluster but not persisted and WithWait is ignored
+func (c *Client[TItem, TList]) DeleteAllOf(ctx context.Context, namespace, selector string, opts ...Option) error {
+	return c.intercept(ctx, ActionDeleteAllOf, namespace, "", nil, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
+
+		if err != nil {
+			return err
+		}
+
+		labelSelector, err := labels.Parse(selector)
+
+		if err != nil {
+			return fmt.Errorf("unable to parse label selector %q. %v", selector, err)
+		}
+
+		if namespace == "" {
+			namespaced, err := c.uncachedClient.IsObjectNamespaced(c.newResource())
+
+			if err != nil {
+				return fmt.Errorf("unable to determine scope of %v. %v", c.resourceType, err)
+			}
+
+			if namespaced {
+				return fmt.Errorf("unable to delete all of %v. a namespace is required as %v is namespaced", c.resourceType, c.resourceType)
+			}
+		}
+
+		o := newWriteOptions(ctx, opts)
+
+		var matched []runtime.Object
+
+		if o.wait && !o.dryRun {
+			if c.listErr != nil {
+				return c.listErr
+			}
+
+			resourceList := c.newResourceList()
+
+			if err := c.uncachedClient.List(ctx, resourceList, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+				return fmt.Errorf("unable to list resources to delete. %w", err)
+			}
+
+			if matched, err = meta.ExtractList(resourceList); err != nil {
+				return fmt.Errorf("unable to extract items from resource list. %v", err)
+			}
+		}
+
+		deleteAllOfOpts := []client.DeleteAllOfOption{
+			client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: labelSelector},
+		}
+
+		for _, deleteOpt := range o.deleteOptions() {
+			deleteAllOfOpts = append(deleteAllOfOpts, deleteOpt)
+		}
+
+		if err := clt.DeleteAllOf(ctx, c.newResource(), deleteAllOfOpts...); err != nil {
+			return err
+		}
+
+		if o.dryRun {
+			obs.LogFunc(ctx, 2, "kapi.client dry-run", "type", "kapi_client_dry_run", "resource_action", ActionDeleteAllOf, "resource_type", c.resourceType, "resource_namespace", namespace, "label_selector", selector)
+			return nil
+		}
+
+		for _, obj := range matched {
+			resource, ok := obj.(client.Object)
+
+			if !ok {
+				continue
+			}
+
+			if err := c.WaitForDeletion(ctx, resource.GetNamespace(), resource.GetName()); err != nil {
+				return err
+			}
+		}
 
-	return resource, clt.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, resource)
+		return nil
+	})
 }
 
-// List returns data describing all occurences of the resource type associated with the client
-func (c *Client[TItem, TList]) List(ctx context.Context) (TList, error) {
-	resourceList := reflect.New(c.resourceListType).Interface().(TList)
+// Get returns data describing the specified resource.
+//
+// If WithoutCache is passed, the resource is read directly from the cluster, even if the client is cached
+func (c *Client[TItem, TList]) Get(ctx context.Context, namespace, name string, opts ...Option) (TItem, error) {
+	resource := c.newResource()
 
-	defer c.observe(ctx, "list", resourceList)()
+	return resource, c.intercept(ctx, ActionGet, namespace, name, resource, func(ctx context.Context) error {
+		o := newOptions(opts)
 
-	clt, err := c.getClient()
+		clt, err := c.getClient(o.bypassCache)
 
-	if err != nil {
-		return resourceList, err
-	}
+		if err != nil {
+			return err
+		}
+
+		if c.readYourWritesTimeout > 0 && c.cached && !o.bypassCache {
+			return c.consistentGet(ctx, clt, types.NamespacedName{Namespace: namespace, Name: name}, resource)
+		}
 
-	return resourceList, clt.List(ctx, resourceList)
+		return clt.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, resource)
+	})
+}
+
+// GetByName returns data describing the specified cluster-scoped resource.
+//
+// The same options as Get are supported
+func (c *Client[TItem, TList]) GetByName(ctx context.Context, name string, opts ...Option) (TItem, error) {
+	return c.Get(ctx, "", name, opts...)
+}
+
+// List returns data describing all occurences of the resource type associated with the client.
+//
+// If WithoutCache is passed, the resources are read directly from the cluster, even if the client is cached
+func (c *Client[TItem, TList]) List(ctx context.Context, opts ...Option) (TList, error) {
+	resourceList := c.newResourceList()
+
+	return resourceList, c.intercept(ctx, ActionList, "", "", resourceList, func(ctx context.Context) error {
+		o := newOptions(opts)
+
+		clt, err := c.getClient(o.bypassCache)
+
+		if err != nil {
+			return err
+		}
+
+		if c.listErr != nil {
+			return c.listErr
+		}
+
+		if c.readYourWritesTimeout > 0 && c.cached && !o.bypassCache {
+			return c.consistentList(ctx, clt, resourceList)
+		}
+
+		return clt.List(ctx, resourceList)
+	})
 }
 
 // ClientFor returns a Client that can be used to perform various IO operations against resources on a k8s cluster
@@ -111,44 +396,60 @@ func (c *Client[TItem, TList]) List(ctx context.Context) (TList, error) {
 // be used by default.
 //
 // However, as there can be a delay before the latest resource state is available in the cache, some clients may need to
-// disable it in order to retrieve the latest resource state from the cluster.
-func ClientFor[TItem client.Object, TList client.ObjectList](ctx context.Context, cluster *Cluster, cache bool) *Client[TItem, TList] {
+// disable it in order to retrieve the latest resource state from the cluster. Alternatively, WithoutCache can be passed to
+// individual reads on a cached client.
+//
+// Uncached clients share a single underlying connection to the cluster, so are inexpensive to create and use.
+//
+// ClientOptions can be passed to further configure the client; for example to enable read-your-writes consistency with WithReadYourWrites.
+func ClientFor[TItem client.Object, TList client.ObjectList](ctx context.Context, cluster *Cluster, cache bool, opts ...ClientOption) *Client[TItem, TList] {
 	var (
 		zeroOfTItem TItem
 		zeroOfTList TList
 	)
 
-	obs.LogFunc(ctx, 3, "creating kapi.client", "resource_type", fmt.Sprintf("%T", zeroOfTItem), "resource_list_type", fmt.Sprintf("%T", zeroOfTList))
+	return newClient(ctx, cluster, cache,
+		func() TItem { return reflect.New(reflect.TypeOf(zeroOfTItem).Elem()).Interface().(TItem) },
+		func() TList { return reflect.New(reflect.TypeOf(zeroOfTList).Elem()).Interface().(TList) },
+		fmt.Sprintf("%T", zeroOfTItem),
+		fmt.Sprintf("%T", zeroOfTList),
+		opts,
+	)
+}
 
-	return &Client[TItem, TList]{
-		getClient: func() (client.Client, error) {
-			if !cluster.connected {
-				panic("kapi.client used before kapi.cluster.connect called")
-			}
-			if !cache {
-				return client.New(cluster.manager.GetConfig(), client.Options{
-					Scheme: cluster.manager.GetScheme(),
-				})
-			}
-			return cluster.manager.GetClient(), nil
-		},
-		resourceType:     reflect.TypeOf(zeroOfTItem).Elem(),
-		resourceListType: reflect.TypeOf(zeroOfTList).Elem(),
+func newClient[TItem client.Object, TList client.ObjectList](ctx context.Context, cluster *Cluster, cache bool, newResource func() TItem, newResourceList func() TList, resourceType, resourceListType string, opts []ClientOption) *Client[TItem, TList] {
+	o := clientOptions{}
+
+	for _, opt := range opts {
+		opt(&o)
 	}
-}
 
-func (c *Client[TItem, TList]) observe(ctx context.Context, act string, obj runtime.Object) func() {
-	stopTimer := obs.MetricTimerFunc(ctx, "kapi_client")
+	obs.LogFunc(ctx, 3, "creating kapi.client", "resource_type", resourceType, "resource_list_type", resourceListType)
+
+	c := &Client[TItem, TList]{
+		cluster:               cluster,
+		uncachedClient:        cluster.uncachedClient,
+		cached:                cache,
+		readYourWritesTimeout: o.readYourWritesTimeout,
+		newResource:           newResource,
+		newResourceList:       newResourceList,
+		resourceType:          resourceType,
+		resourceListType:      resourceListType,
+	}
 
-	var (
-		zeroOfTItem TItem
-		zeroOfTList TList
-	)
+	c.interceptors = slices.Concat(cluster.interceptors, o.interceptors)
 
-	obs.LogFunc(ctx, 1, "kapi.client invoked", "type", "kapi_client_summary", "resource_action", act, "resource_type", fmt.Sprintf("%T", zeroOfTItem), "resource_list_type", fmt.Sprintf("%T", zeroOfTList))
+	return c
+}
+
+func (c *Client[TItem, TList]) getClient(bypassCache bool) (client.Client, error) {
+	if !c.cluster.connected {
+		panic("kapi.client used before kapi.cluster.connect called")
+	}
 
-	return func() {
-		obs.LogFunc(ctx, 3, "kapi.client invoked", "type", "kapi_client_trace", "resource_action", act, "resource_type", fmt.Sprintf("%T", zeroOfTItem), "resource_list_type", fmt.Sprintf("%T", zeroOfTList), "resource", fmt.Sprintf("+%v", obj))
-		stopTimer("resource_type", fmt.Sprintf("%T", zeroOfTItem), "resource_action", act)
+	if !c.cached || bypassCache {
+		return c.uncachedClient, nil
 	}
+
+	return c.cluster.manager.GetClient(), nil
 }
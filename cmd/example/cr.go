@@ -7,6 +7,6 @@ type (
 	ConfigAuditList = kapi.CustomResourceList[*ConfigAudit]
 
 	ConfigAuditSpec struct {
-		Message string `json:"message"`
+		Message string `json:"message" kapi:"column=Message"`
 	}
 )
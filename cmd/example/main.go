@@ -47,6 +47,11 @@ func main() {
 					"ConfigAudit":     &ConfigAudit{},
 					"ConfigAuditList": &ConfigAuditList{},
 				},
+				Options: map[string]kapi.KindOptions{
+					"ConfigAudit": {
+						ShortNames: []string{"ca"},
+					},
+				},
 			},
 		},
 	})
@@ -18,7 +18,8 @@ func addReconcilerExample(ctx context.Context, k *kapi.Cluster) error {
 
 	return kapi.AddReconciler(ctx, k, filterFunc, func(ctx context.Context, evt kapi.ReconcileEventType, cfgMap *corev1.ConfigMap) error {
 
-		klient := kapi.ClientFor[*ConfigAudit, *ConfigAuditList](ctx, k, true)
+		// read-your-writes consistency ensures the list below reflects audits created by previous invocations, even if the cache has not yet caught up
+		klient := kapi.For[*ConfigAudit](ctx, k, true, kapi.WithReadYourWrites(time.Second*2))
 
 		cfgAudits, err := klient.List(ctx)
 
@@ -29,7 +30,7 @@ func addReconcilerExample(ctx context.Context, k *kapi.Cluster) error {
 		cfgAudit := ConfigAudit{}
 		cfgAudit.Name = fmt.Sprintf("configaudit-%v", time.Now().UnixMicro())
 		cfgAudit.Namespace = "kapi-example"
-		cfgAudit.Spec.Message = fmt.Sprintf("configmap %v created. previous audit count was %v", cfgMap.Name, len(cfgAudits.Items))
+		cfgAudit.Spec.Message = fmt.Sprintf("configmap %v created. previous audit count was %v", cfgMap.Name, len(cfgAudits))
 
 		return klient.Create(ctx, &cfgAudit)
 	})
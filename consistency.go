@@ -0,0 +1,278 @@
+package kapi
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// writeTracker records the resourceVersions returned by writes made through cached clients with read-your-writes consistency enabled.
+	// It is shared by all clients of a kapi.Cluster, so writes made by one client are observed by reads made by another
+	writeTracker struct {
+		mu     sync.Mutex
+		writes map[writeKey]trackedWrite
+	}
+	writeKey struct {
+		resourceType string
+		types.NamespacedName
+	}
+	trackedWrite struct {
+		resourceVersion string
+		deleted         bool
+		recordedAt      time.Time
+	}
+)
+
+const (
+	// writeTrackingTTL limits how long a write is tracked; the cache is expected to have caught up long before it elapses
+	writeTrackingTTL = time.Minute
+	// cachePollInterval defines how frequently the cache is re-read while waiting for it to catch up with a write
+	cachePollInterval = time.Millisecond * 10
+)
+
+// WithReadYourWrites enables read-your-writes consistency for a cached client.
+//
+// After a resource is created, updated or deleted through any client of the cluster with this option, reads of that resource through
+// such a client block until the cache reflects the write. If the cache has not caught up within the timeout, the read falls back to
+// reading directly from the cluster.
+//
+// It has no effect on uncached clients, which are always consistent with the cluster.
+func WithReadYourWrites(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.readYourWritesTimeout = timeout
+	}
+}
+
+func newWriteTracker() *writeTracker {
+	return &writeTracker{
+		writes: map[writeKey]trackedWrite{},
+	}
+}
+
+func (wt *writeTracker) record(resourceType string, resource client.Object, deleted bool) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	now := time.Now()
+
+	maps.DeleteFunc(wt.writes, func(_ writeKey, w trackedWrite) bool {
+		return now.Sub(w.recordedAt) > writeTrackingTTL
+	})
+
+	wt.writes[writeKey{resourceType: resourceType, NamespacedName: client.ObjectKeyFromObject(resource)}] = trackedWrite{
+		resourceVersion: resource.GetResourceVersion(),
+		deleted:         deleted,
+		recordedAt:      now,
+	}
+}
+
+// pending returns the tracked writes for the resource type, optionally limited to a single resource
+func (wt *writeTracker) pending(resourceType string, key *types.NamespacedName) map[writeKey]trackedWrite {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	pending := map[writeKey]trackedWrite{}
+
+	for k, w := range maps.All(wt.writes) {
+		if k.resourceType == resourceType && (key == nil || k.NamespacedName == *key) {
+			pending[k] = w
+		}
+	}
+
+	return pending
+}
+
+// forget removes tracked writes once they are reflected by the cache, unless they have since been superseded by a later write
+func (wt *writeTracker) forget(satisfied map[writeKey]trackedWrite) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	for k, w := range maps.All(satisfied) {
+		if current, ok := wt.writes[k]; ok && current == w {
+			delete(wt.writes, k)
+		}
+	}
+}
+
+// reflects returns true if the resource state read from the cache reflects the tracked write
+func (w trackedWrite) reflects(resource client.Object, exists bool) bool {
+	if w.deleted {
+		return !exists
+	}
+
+	if !exists {
+		return false
+	}
+
+	if resource.GetResourceVersion() == w.resourceVersion {
+		return true
+	}
+
+	// resourceVersions are opaque, but are in practice monotonically increasing integers; where they are not, only equality is considered
+	cached, err1 := strconv.ParseUint(resource.GetResourceVersion(), 10, 64)
+	written, err2 := strconv.ParseUint(w.resourceVersion, 10, 64)
+
+	return err1 == nil && err2 == nil && cached >= written
+}
+
+// cacheHolds returns false if the cache config of the resource's type excludes it from the cache; writes to such resources are not tracked
+// as the cache would never reflect them
+func (cluster *Cluster) cacheHolds(resource client.Object) bool {
+	scope, ok := cluster.cacheScopes[reflect.TypeOf(resource)]
+
+	if !ok {
+		scope = cluster.cacheScope
+	}
+
+	return scope.holds(resource)
+}
+
+func (c *Client[TItem, TList]) recordWrite(resource TItem, deleted bool) {
+	if c.readYourWritesTimeout > 0 && c.cached && c.cluster.cacheHolds(resource) {
+		c.cluster.writes.record(c.resourceType, resource, deleted)
+	}
+}
+
+// consistentRead invokes read, which reads from the cache, until the result reflects the pending writes or the read-your-writes timeout
+// elapses. It returns false if the timeout elapsed, in which case the caller should read directly from the cluster
+func (c *Client[TItem, TList]) consistentRead(ctx context.Context, pending map[writeKey]trackedWrite, read func() (reflected bool, err error)) (bool, error) {
+	deadline := time.Now().Add(c.readYourWritesTimeout)
+
+	for {
+		reflected, err := read()
+
+		if err != nil && !IsNotFound(err) {
+			return true, err
+		}
+
+		if reflected {
+			c.cluster.writes.forget(pending)
+			return true, err
+		}
+
+		if time.Now().After(deadline) {
+			obs.LogFunc(ctx, 2, "kapi.client cache did not reflect writes within timeout. reading from cluster", "resource_type", c.resourceType, "timeout", c.readYourWritesTimeout.String())
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-time.After(cachePollInterval):
+		}
+	}
+}
+
+func (c *Client[TItem, TList]) consistentGet(ctx context.Context, clt client.Client, key types.NamespacedName, resource TItem) error {
+	pending := c.cluster.writes.pending(c.resourceType, &key)
+
+	if len(pending) == 0 {
+		return clt.Get(ctx, key, resource)
+	}
+
+	consistent, err := c.consistentRead(ctx, pending, func() (bool, error) {
+		err := clt.Get(ctx, key, resource)
+
+		for _, w := range maps.All(pending) {
+			if !w.reflects(resource, err == nil) {
+				return false, err
+			}
+		}
+
+		return true, err
+	})
+
+	if consistent {
+		return err
+	}
+
+	err = c.uncachedClient.Get(ctx, key, resource)
+
+	if err == nil || IsNotFound(err) {
+		c.cluster.writes.forget(reflectedBy(pending, func(key types.NamespacedName) (client.Object, bool) {
+			return resource, err == nil
+		}))
+	}
+
+	return err
+}
+
+func (c *Client[TItem, TList]) consistentList(ctx context.Context, clt client.Client, resourceList TList, opts ...client.ListOption) error {
+	pending := c.cluster.writes.pending(c.resourceType, nil)
+
+	if len(pending) == 0 {
+		return clt.List(ctx, resourceList, opts...)
+	}
+
+	consistent, err := c.consistentRead(ctx, pending, func() (bool, error) {
+		if err := clt.List(ctx, resourceList, opts...); err != nil {
+			return false, err
+		}
+
+		lookup, err := listLookup(resourceList)
+
+		if err != nil {
+			return false, err
+		}
+
+		return len(reflectedBy(pending, lookup)) == len(pending), nil
+	})
+
+	if consistent {
+		return err
+	}
+
+	if err := c.uncachedClient.List(ctx, resourceList, opts...); err != nil {
+		return err
+	}
+
+	// writes confirmed by the cluster are no longer tracked, so that writes the cache never reflects block only a single read
+	if lookup, err := listLookup(resourceList); err == nil {
+		c.cluster.writes.forget(reflectedBy(pending, lookup))
+	}
+
+	return nil
+}
+
+// listLookup returns a func that looks up the resources of the list by key
+func listLookup(resourceList client.ObjectList) (func(key types.NamespacedName) (client.Object, bool), error) {
+	items, err := meta.ExtractList(resourceList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[types.NamespacedName]client.Object, len(items))
+
+	for _, item := range items {
+		if resource, ok := item.(client.Object); ok {
+			listed[client.ObjectKeyFromObject(resource)] = resource
+		}
+	}
+
+	return func(key types.NamespacedName) (client.Object, bool) {
+		resource, exists := listed[key]
+		return resource, exists
+	}, nil
+}
+
+// reflectedBy returns the pending writes that are reflected by the resources returned by lookup
+func reflectedBy(pending map[writeKey]trackedWrite, lookup func(key types.NamespacedName) (client.Object, bool)) map[writeKey]trackedWrite {
+	reflected := map[writeKey]trackedWrite{}
+
+	for k, w := range maps.All(pending) {
+		if resource, exists := lookup(k.NamespacedName); w.reflects(resource, exists) {
+			reflected[k] = w
+		}
+	}
+
+	return reflected
+}
@@ -0,0 +1,342 @@
+package kapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type (
+	conversionKey struct {
+		from, to schema.GroupVersionKind
+	}
+	conversionFunc func(from runtime.Object) (runtime.Object, error)
+	// conversions converts resources between the versions of their kinds, with the funcs added by AddConversion, and serves the conversion webhook
+	conversions struct {
+		scheme     *runtime.Scheme
+		hubs       map[schema.GroupKind]string
+		versions   map[schema.GroupKind][]string
+		funcs      map[conversionKey]conversionFunc
+		registered bool
+	}
+)
+
+const (
+	// conversionPath is the path on which the conversion webhook is served
+	conversionPath = "/convert"
+)
+
+func newConversions(scheme *runtime.Scheme, crds []CRDs) *conversions {
+	c := &conversions{
+		scheme:   scheme,
+		hubs:     map[schema.GroupKind]string{},
+		versions: map[schema.GroupKind][]string{},
+		funcs:    map[conversionKey]conversionFunc{},
+	}
+
+	for gk, kindVersions := range kindVersionsOf(crds) {
+		if len(kindVersions) < 2 {
+			continue
+		}
+
+		for kv := range slices.Values(kindVersions) {
+			c.versions[gk] = append(c.versions[gk], kv.crd.APIVersion)
+		}
+
+		// kinds without a storage version can be served where their CustomResourceDefinitions are defined externally, so are only
+		// rejected if conversions are added for them
+		if storage, err := storageVersionOf(kindVersions); err == nil {
+			c.hubs[gk] = storage.crd.APIVersion
+		}
+	}
+
+	return c
+}
+
+// AddConversion registers a func that converts resources from one version of a kind to another, for a kind defined in more than one version.
+//
+// Conversions are made through the storage version of the kind, known as the hub, so a conversion to and from the hub must be added for each other
+// version; for example, where v1 is the storage version, `func(from *ExampleV1Alpha1) (*ExampleV1, error)` and `func(from *ExampleV1) (*ExampleV1Alpha1, error)`.
+// The func need only convert the fields that differ between versions, such as the Spec and Status; the metadata of the source resource is copied to the result.
+//
+// The funcs are invoked by a conversion webhook served on the path `/convert` of the webhook server. See ClusterConfig.Webhooks to include the
+// webhook in generated CustomResourceDefinitions
+func AddConversion[TFrom, TTo client.Object](ctx context.Context, cluster *Cluster, fn func(from TFrom) (TTo, error)) error {
+	if cluster.connected {
+		panic("kapi.add-conversion must be called before kapi.cluster.connect")
+	}
+
+	var (
+		zeroOfTFrom TFrom
+		zeroOfTTo   TTo
+	)
+
+	defer obs.MetricTimerFunc(ctx, "kapi_add_conversion")("from_type", fmt.Sprintf("%T", zeroOfTFrom), "to_type", fmt.Sprintf("%T", zeroOfTTo))
+	obs.LogFunc(ctx, 3, "creating kapi.conversion", "from_type", fmt.Sprintf("%T", zeroOfTFrom), "to_type", fmt.Sprintf("%T", zeroOfTTo))
+
+	from, err := apiutil.GVKForObject(reflect.New(reflect.TypeOf(zeroOfTFrom).Elem()).Interface().(TFrom), cluster.manager.GetScheme())
+
+	if err != nil {
+		return fmt.Errorf("unable to add conversion from %T. %v", zeroOfTFrom, err)
+	}
+
+	to, err := apiutil.GVKForObject(reflect.New(reflect.TypeOf(zeroOfTTo).Elem()).Interface().(TTo), cluster.manager.GetScheme())
+
+	if err != nil {
+		return fmt.Errorf("unable to add conversion to %T. %v", zeroOfTTo, err)
+	}
+
+	if from.GroupKind() != to.GroupKind() || from == to {
+		return fmt.Errorf("unable to add conversion from %v to %v. conversions must be between different versions of the same kind", from, to)
+	}
+
+	if len(cluster.conversions.versions[from.GroupKind()]) == 0 {
+		return fmt.Errorf("unable to add conversion from %v to %v. kind %v is not defined in more than one version", from, to, from.GroupKind())
+	}
+
+	if _, ok := cluster.conversions.hubs[from.GroupKind()]; !ok {
+		return fmt.Errorf("unable to add conversion from %v to %v. no version of kind %v is marked as storage", from, to, from.GroupKind())
+	}
+
+	cluster.conversions.funcs[conversionKey{from: from, to: to}] = newConversionFunc(fn, to)
+
+	if !cluster.conversions.registered {
+		cluster.manager.GetWebhookServer().Register(conversionPath, cluster.conversions)
+		cluster.conversions.registered = true
+	}
+
+	return nil
+}
+
+// newConversionFunc returns a conversionFunc that invokes fn and sets the metadata and type of its result
+func newConversionFunc[TFrom, TTo client.Object](fn func(from TFrom) (TTo, error), to schema.GroupVersionKind) conversionFunc {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		resource, ok := obj.(TFrom)
+
+		if !ok {
+			var zeroOfTFrom TFrom
+			return nil, fmt.Errorf("conversion from %T was passed type of %T", zeroOfTFrom, obj)
+		}
+
+		converted, err := fn(resource)
+
+		if err != nil {
+			return nil, err
+		}
+
+		copyObjectMeta(converted, resource)
+		converted.GetObjectKind().SetGroupVersionKind(to)
+
+		return converted, nil
+	}
+}
+
+// validate returns an error if any version of a kind, other than the hub, lacks a conversion to or from the hub. Kinds without any conversions are not validated
+func (c *conversions) validate() error {
+	for gk, hub := range c.hubs {
+		if !c.hasConversions(gk) {
+			continue
+		}
+
+		hubGVK := gk.WithVersion(hub)
+
+		for version := range slices.Values(c.versions[gk]) {
+			if version == hub {
+				continue
+			}
+
+			for key := range slices.Values([]conversionKey{{from: gk.WithVersion(version), to: hubGVK}, {from: hubGVK, to: gk.WithVersion(version)}}) {
+				if _, ok := c.funcs[key]; !ok {
+					return fmt.Errorf("no conversion from %v to %v has been added", key.from, key.to)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *conversions) hasConversions(gk schema.GroupKind) bool {
+	for key := range c.funcs {
+		if key.from.GroupKind() == gk {
+			return true
+		}
+	}
+
+	return false
+}
+
+// convert converts the passed resource to the specified version of its kind, either directly or through the hub of its kind
+func (c *conversions) convert(obj runtime.Object, to schema.GroupVersionKind) (runtime.Object, error) {
+	from := obj.GetObjectKind().GroupVersionKind()
+
+	if from == to {
+		return obj, nil
+	}
+
+	if fn, ok := c.funcs[conversionKey{from: from, to: to}]; ok {
+		return fn(obj)
+	}
+
+	hub := from.GroupKind().WithVersion(c.hubs[from.GroupKind()])
+	toHub, toHubOK := c.funcs[conversionKey{from: from, to: hub}]
+	fromHub, fromHubOK := c.funcs[conversionKey{from: hub, to: to}]
+
+	if !toHubOK || !fromHubOK {
+		return nil, fmt.Errorf("no conversion from %v to %v has been added", from, to)
+	}
+
+	obj, err := toHub(obj)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fromHub(obj)
+}
+
+// ServeHTTP serves the conversion webhook, converting the resources of a ConversionReview to the desired version
+func (c *conversions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	review := apiextensionsv1.ConversionReview{}
+
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Request == nil {
+		obs.LogFunc(ctx, 0, "invalid conversion review received by kapi.conversion", "error", fmt.Sprint(err))
+		http.Error(w, "invalid conversion review", http.StatusBadRequest)
+		return
+	}
+
+	desiredAPIVersion := review.Request.DesiredAPIVersion
+	stopTimer := obs.MetricTimerFunc(ctx, "kapi_conversion")
+	obs.LogFunc(ctx, 1, "kapi.conversion invoked", "type", "kapi_conversion_summary", "desired_api_version", desiredAPIVersion, "objects", len(review.Request.Objects))
+
+	review.Response = c.review(review.Request)
+	review.Request = nil
+	review.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("ConversionReview"))
+
+	stopTimer("desired_api_version", desiredAPIVersion, "result", review.Response.Result.Status)
+
+	if review.Response.Result.Status != metav1.StatusSuccess {
+		obs.LogFunc(ctx, 0, "kapi.conversion failed", "type", "kapi_conversion_summary", "error", review.Response.Result.Message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		obs.LogFunc(ctx, 0, "unable to write conversion review response", "error", err.Error())
+	}
+}
+
+func (c *conversions) review(req *apiextensionsv1.ConversionRequest) *apiextensionsv1.ConversionResponse {
+	resp := &apiextensionsv1.ConversionResponse{UID: req.UID, Result: metav1.Status{Status: metav1.StatusSuccess}}
+
+	failed := func(err error) *apiextensionsv1.ConversionResponse {
+		return &apiextensionsv1.ConversionResponse{UID: req.UID, Result: metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}}
+	}
+
+	gv, err := schema.ParseGroupVersion(req.DesiredAPIVersion)
+
+	if err != nil {
+		return failed(fmt.Errorf("invalid desired api version %q. %v", req.DesiredAPIVersion, err))
+	}
+
+	for object := range slices.Values(req.Objects) {
+		converted, err := c.convertRaw(object.Raw, gv)
+
+		if err != nil {
+			return failed(err)
+		}
+
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: converted})
+	}
+
+	return resp
+}
+
+func (c *conversions) convertRaw(raw []byte, gv schema.GroupVersion) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("unable to decode type of resource. %v", err)
+	}
+
+	from := typeMeta.GroupVersionKind()
+	obj, err := c.scheme.New(from)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create resource of kind %v. %v", from, err)
+	}
+
+	if err := json.Unmarshal(raw, obj); err != nil {
+		return nil, fmt.Errorf("unable to decode resource of kind %v. %v", from, err)
+	}
+
+	obj.GetObjectKind().SetGroupVersionKind(from)
+
+	converted, err := c.convert(obj, gv.WithKind(from.Kind))
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert resource of kind %v. %v", from, err)
+	}
+
+	return json.Marshal(converted)
+}
+
+// conversion returns the conversion strategy of CustomResourceDefinitions with more than one version; a webhook where a Service is defined, otherwise none
+func (w WebhookConfig) conversion() *apiextensionsv1.CustomResourceConversion {
+	if w.ServiceName == "" {
+		return &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+	}
+
+	port := w.port()
+
+	return &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: w.ServiceNamespace,
+					Name:      w.ServiceName,
+					Path:      ptrTo(conversionPath),
+					Port:      &port,
+				},
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+}
+
+// copyObjectMeta copies the ObjectMeta of src to dst, where both define one
+func copyObjectMeta(dst, src runtime.Object) {
+	dstMeta, srcMeta := objectMetaOf(dst), objectMetaOf(src)
+
+	if dstMeta.IsValid() && srcMeta.IsValid() {
+		dstMeta.Set(reflect.ValueOf(*srcMeta.Addr().Interface().(*metav1.ObjectMeta).DeepCopy()))
+	}
+}
+
+func objectMetaOf(obj runtime.Object) reflect.Value {
+	v := reflect.ValueOf(obj)
+
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	objectMeta := v.Elem().FieldByName("ObjectMeta")
+
+	if !objectMeta.IsValid() || objectMeta.Type() != objectMetaType {
+		return reflect.Value{}
+	}
+
+	return objectMeta
+}
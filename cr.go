@@ -1,9 +1,7 @@
 package kapi
 
 import (
-	"encoding/json"
-	"reflect"
-
+	"github.com/comradequinn/kapi/internal/deepcopy"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -13,12 +11,7 @@ type (
 	// FieldUndefined is used to indicate that a CustomResource does not define a particular conventional field
 	FieldUndefined *struct{}
 
-	// ListUndefined is used to indicate that a CustomResource does not have a list form, or the form is not relevant to the current use-case
-	ListUndefined struct {
-		CustomResourceList[*CustomResource[FieldUndefined, FieldUndefined, FieldUndefined]]
-	}
-
-	// CustomResource defines a template for a struct that represents a K8s CustomResource with the conventional fields of Spec, Status and Scale.
+	// CustomResource defines a template for a struct that represents a K8s CustomResource with the conventional fields of Spec and Status.
 	// The typical use-case is to embed it in a descriptively named struct that represents the CR itself.
 	//
 	// For example, the below defines an CR named ExampleResource that only exposes a Spec field.
@@ -35,16 +28,19 @@ type (
 	//
 	//   - 'Spec' defines the main properties of the resource; its desired state.
 	//   - 'Status', typically configured as a subresource in the CustomResourceDefinition, defines the current state.
-	//   - 'Scale', typically configured as a subresource in the CustomResourceDefinition, defines the scaling properties of the resource.
 	//
-	// Where any of the above fields are not required, they should be set to the type kapi.FieldUndefined
+	// Where either of the above fields are not required, they should be set to the type kapi.FieldUndefined.
+	//
+	// The scale subresource is not a field of the resource, but a view of its replica fields, mapped by KindOptions.Scale. It is accessed with
+	// Client.GetScale and Client.UpdateScale. The TScale type parameter is retained for compatibility and should be set to kapi.FieldUndefined
 	CustomResource[TSpec any, TStatus any, TScale any] struct {
 		metav1.TypeMeta   `json:",inline"`
 		metav1.ObjectMeta `json:"metadata,omitempty"`
 
 		Spec   TSpec   `json:"spec,omitempty"`
 		Status TStatus `json:"status,omitempty"`
-		Scale  TScale  `json:"scale,omitempty"`
+		// Deprecated: Scale is not serialised. Use KindOptions.Scale to map the scale subresource to fields of the Spec and Status
+		Scale TScale `json:"-"`
 	}
 
 	// CustomResourceList defines a template for the list representation of zero or more CustomResource[T, T, T] items
@@ -55,30 +51,24 @@ type (
 	}
 )
 
+// DeepCopyObject returns a deep copy of the CustomResourceList.
+//
+// Spec and Status types can provide a `DeepCopyInto` method to control how they are copied, otherwise they are copied field by field
 func (e *CustomResourceList[T]) DeepCopyObject() runtime.Object {
 	if e == nil {
 		return nil
 	}
 
-	var out *CustomResourceList[T]
-	out = reflect.New(reflect.TypeOf(out).Elem()).Interface().(*CustomResourceList[T])
-
-	b, _ := json.Marshal(e)
-	json.Unmarshal(b, out)
-
-	return out
+	return deepcopy.Copy(e)
 }
 
+// DeepCopyObject returns a deep copy of the CustomResource.
+//
+// Spec and Status types can provide a `DeepCopyInto` method to control how they are copied, otherwise they are copied field by field
 func (e *CustomResource[TSpec, TStatus, TScale]) DeepCopyObject() runtime.Object {
 	if e == nil {
 		return nil
 	}
 
-	var out *CustomResource[TSpec, TStatus, TScale]
-	out = reflect.New(reflect.TypeOf(out).Elem()).Interface().(*CustomResource[TSpec, TStatus, TScale])
-
-	b, _ := json.Marshal(e)
-	json.Unmarshal(b, out)
-
-	return out
+	return deepcopy.Copy(e)
 }
@@ -0,0 +1,440 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// KindOptions defines how a kind is represented in its generated CustomResourceDefinition
+	KindOptions struct {
+		// Plural defines the plural name of the kind, used in its API path. By default, this is derived from the lowercase kind name; for example `examples` for `Example`
+		Plural string
+		// ClusterScoped declares that resources of the kind are not namespaced. Cluster-scoped resources are always cached across the
+		// whole cluster, irrespective of ClusterConfig.Namespaces, and are accessed with the name-only Client methods, such as GetByName
+		ClusterScoped bool
+		// Scale, where set, enables the scale subresource for the kind. This allows it to be scaled by `kubectl scale`, a HorizontalPodAutoscaler or Client.UpdateScale
+		Scale *ScaleOptions
+		// ShortNames defines abbreviated names by which the kind can be referred to with kubectl; for example `ex` for `kubectl get ex`
+		ShortNames []string
+		// Categories defines the groups of resources the kind belongs to, so that it is listed by `kubectl get <category>`; for example `all`
+		Categories []string
+		// PrinterColumns defines the columns, in addition to those declared with the `column=<name>` option of the `kapi` tag on fields of the kind, that
+		// `kubectl get` displays for the kind.
+		//
+		// Where any columns are defined, an Age column is added after them, unless a column of that name is defined
+		PrinterColumns []PrinterColumn
+	}
+	// PrinterColumn defines a column displayed by `kubectl get`.
+	//
+	// Columns can also be declared with the `column=<name>` option of the `kapi` tag on a field of the kind, such as `kapi:"column=Replicas"`; which infers
+	// the JSONPath and Type from the field. Adding the `wide` option, such as `kapi:"column=Replicas,wide"`, limits the column to `kubectl get -o wide`
+	PrinterColumn struct {
+		// Name defines the heading of the column
+		Name string
+		// JSONPath defines the field displayed in the column; for example `.status.phase`
+		JSONPath string
+		// Type defines the OpenAPI type of the field; one of `string`, `integer`, `number`, `boolean` or `date`. By default, this is `string`
+		Type string
+		// Description optionally describes the column
+		Description string
+		// Priority defines the importance of the column. Columns with a priority greater than 0 are only displayed by `kubectl get -o wide`
+		Priority int32
+	}
+	// ScaleOptions defines the fields of a kind that are mapped to the scale subresource, as JSON paths; for example `.spec.replicas`
+	ScaleOptions struct {
+		// SpecReplicasPath defines the field that holds the desired number of replicas. By default, this is `.spec.replicas`
+		SpecReplicasPath string
+		// StatusReplicasPath defines the field that holds the observed number of replicas. By default, this is `.status.replicas`
+		StatusReplicasPath string
+		// LabelSelectorPath optionally defines the field that holds the label selector, in string form, used by a HorizontalPodAutoscaler to identify the pods of the resource
+		LabelSelectorPath string
+	}
+	// kindVersion identifies the definition of a kind in a specific version
+	kindVersion struct {
+		crd      CRDs
+		kindName string
+		kindType KindType
+	}
+)
+
+// CustomResourceDefinitions returns the CustomResourceDefinitions of the kinds defined in the CRDs of the ClusterConfig.
+//
+// The OpenAPI schema of each kind is generated from the fields, and json tags, of its Go type. The status subresource is enabled for
+// kinds that define a Status and the scale subresource for kinds with KindOptions.Scale set. List kinds are not included.
+//
+// A kind defined in more than one CRDs entry of the same APIGroup is generated as a single CustomResourceDefinition that serves each version;
+// the version in the entry marked as Storage is stored. Where ClusterConfig.Webhooks.ServiceName is set, such kinds are converted between versions
+// by the funcs added with AddConversion, otherwise only their apiVersion is changed.
+//
+// The generated CustomResourceDefinitions can be installed by setting ClusterConfig.InstallCRDs, or written out as YAML to be deployed
+// alongside the controller or operator.
+func (cfg ClusterConfig) CustomResourceDefinitions() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid crds config. %w", err)
+	}
+
+	crds := []*apiextensionsv1.CustomResourceDefinition{}
+	kindVersions := kindVersionsOf(cfg.CRDs)
+
+	for _, gk := range slices.SortedFunc(maps.Keys(kindVersions), func(a, b schema.GroupKind) int { return strings.Compare(a.String(), b.String()) }) {
+		customResourceDefinition, err := cfg.customResourceDefinition(kindVersions[gk])
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate custom resource definition for kind %v. %w", gk.Kind, err)
+		}
+
+		crds = append(crds, customResourceDefinition)
+	}
+
+	return crds, nil
+}
+
+func (cfg ClusterConfig) customResourceDefinition(kindVersions []kindVersion) (*apiextensionsv1.CustomResourceDefinition, error) {
+	storage, err := storageVersionOf(kindVersions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []apiextensionsv1.CustomResourceDefinitionVersion{}
+
+	for kv := range slices.Values(kindVersions) {
+		version, err := kv.crdVersion(kv.crd.APIVersion == storage.crd.APIVersion)
+
+		if err != nil {
+			return nil, fmt.Errorf("version %v. %w", kv.crd.APIVersion, err)
+		}
+
+		versions = append(versions, version)
+	}
+
+	kindName, opts := storage.kindName, storage.crd.Options[storage.kindName]
+	plural := storage.crd.plural(kindName)
+	scope := apiextensionsv1.NamespaceScoped
+
+	if opts.ClusterScoped {
+		scope = apiextensionsv1.ClusterScoped
+	}
+
+	var conversion *apiextensionsv1.CustomResourceConversion
+
+	if len(versions) > 1 {
+		conversion = cfg.Webhooks.conversion()
+	}
+
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: plural + "." + storage.crd.APIGroup,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:    storage.crd.APIGroup,
+			Versions: versions,
+			Scope:    scope,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Singular:   strings.ToLower(kindName),
+				Kind:       kindName,
+				ListKind:   kindName + "List",
+				ShortNames: opts.ShortNames,
+				Categories: opts.Categories,
+			},
+			Conversion: conversion,
+		},
+	}, nil
+}
+
+func (kv kindVersion) crdVersion(storage bool) (apiextensionsv1.CustomResourceDefinitionVersion, error) {
+	opts := kv.crd.Options[kv.kindName]
+
+	schema, err := schemaFor(reflect.TypeOf(kv.kindType))
+
+	if err != nil {
+		return apiextensionsv1.CustomResourceDefinitionVersion{}, err
+	}
+
+	version := apiextensionsv1.CustomResourceDefinitionVersion{
+		Name:    kv.crd.APIVersion,
+		Served:  true,
+		Storage: storage,
+		Schema: &apiextensionsv1.CustomResourceValidation{
+			OpenAPIV3Schema: &schema,
+		},
+	}
+
+	if _, ok := schema.Properties["status"]; ok {
+		version.Subresources = &apiextensionsv1.CustomResourceSubresources{
+			Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+		}
+	}
+
+	if opts.Scale != nil {
+		if version.Subresources == nil {
+			version.Subresources = &apiextensionsv1.CustomResourceSubresources{}
+		}
+
+		version.Subresources.Scale = opts.Scale.subresource()
+	}
+
+	if version.AdditionalPrinterColumns, err = printerColumns(reflect.TypeOf(kv.kindType), opts.PrinterColumns); err != nil {
+		return apiextensionsv1.CustomResourceDefinitionVersion{}, err
+	}
+
+	return version, nil
+}
+
+// printerColumns returns the columns declared by the `column=<name>` option of the `kapi` tags of the fields of the type t, and of the structs it contains, followed by the passed columns
+func printerColumns(t reflect.Type, columns []PrinterColumn) ([]apiextensionsv1.CustomResourceColumnDefinition, error) {
+	definitions := []apiextensionsv1.CustomResourceColumnDefinition{}
+
+	if err := addTaggedPrinterColumns(&definitions, t, "", map[reflect.Type]bool{}); err != nil {
+		return nil, err
+	}
+
+	for column := range slices.Values(columns) {
+		if column.Type == "" {
+			column.Type = "string"
+		}
+
+		definitions = append(definitions, apiextensionsv1.CustomResourceColumnDefinition{
+			Name:        column.Name,
+			Type:        column.Type,
+			JSONPath:    column.JSONPath,
+			Description: column.Description,
+			Priority:    column.Priority,
+		})
+	}
+
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+
+	if !slices.ContainsFunc(definitions, func(d apiextensionsv1.CustomResourceColumnDefinition) bool { return d.Name == "Age" }) {
+		definitions = append(definitions, apiextensionsv1.CustomResourceColumnDefinition{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"})
+	}
+
+	return definitions, nil
+}
+
+func addTaggedPrinterColumns(definitions *[]apiextensionsv1.CustomResourceColumnDefinition, t reflect.Type, path string, visiting map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// k8s api types, such as ObjectMeta or Time, do not declare columns
+	if t.Kind() != reflect.Struct || visiting[t] || strings.HasPrefix(t.PkgPath(), "k8s.io/") {
+		return nil
+	}
+
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for field := range structFields(t) {
+		name, _, inline := jsonName(field)
+
+		if name == "-" || field.Type == fieldUndefinedType {
+			continue
+		}
+
+		fieldPath := path
+
+		if !inline {
+			fieldPath = path + "." + name
+		}
+
+		rule, err := parseTagRule(field.Tag.Get("kapi"))
+
+		if err != nil {
+			return fmt.Errorf("invalid kapi tag on field %v. %w", field.Name, err)
+		}
+
+		if rule.column != "" {
+			fieldSchema, err := schemaFor(field.Type)
+
+			if err != nil {
+				return fmt.Errorf("unable to generate column for field %v. %w", field.Name, err)
+			}
+
+			definition := apiextensionsv1.CustomResourceColumnDefinition{Name: rule.column, Type: columnType(fieldSchema), JSONPath: fieldPath}
+
+			if rule.wide {
+				definition.Priority = 1
+			}
+
+			*definitions = append(*definitions, definition)
+		}
+
+		if err := addTaggedPrinterColumns(definitions, field.Type, fieldPath, visiting); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// columnType returns the printer column type of a field with the passed schema
+func columnType(schema apiextensionsv1.JSONSchemaProps) string {
+	switch {
+	case schema.Format == "date-time":
+		return "date"
+	case schema.Type == "integer", schema.Type == "number", schema.Type == "boolean":
+		return schema.Type
+	default:
+		return "string"
+	}
+}
+
+// kindVersionsOf returns the versions in which each kind, excluding list kinds, is defined in the passed CRDs, keyed by group and kind.
+// The versions of each kind are ordered by priority, as they would be by the api server; for example v2, v1, v1beta1, v1alpha1
+func kindVersionsOf(crds []CRDs) map[schema.GroupKind][]kindVersion {
+	kindVersions := map[schema.GroupKind][]kindVersion{}
+
+	for crd := range slices.Values(crds) {
+		for kindName, kindType := range crd.kinds() {
+			if _, ok := kindType.(client.ObjectList); ok {
+				continue
+			}
+
+			gk := schema.GroupKind{Group: crd.APIGroup, Kind: kindName}
+			kindVersions[gk] = append(kindVersions[gk], kindVersion{crd: crd, kindName: kindName, kindType: kindType})
+		}
+	}
+
+	for kvs := range maps.Values(kindVersions) {
+		slices.SortFunc(kvs, func(a, b kindVersion) int {
+			return version.CompareKubeAwareVersionStrings(b.crd.APIVersion, a.crd.APIVersion)
+		})
+	}
+
+	return kindVersions
+}
+
+// storageVersionOf returns the version of a kind that is stored, and acts as the conversion hub; either its only version or that in the CRDs marked as Storage
+func storageVersionOf(kindVersions []kindVersion) (kindVersion, error) {
+	if len(kindVersions) == 1 {
+		return kindVersions[0], nil
+	}
+
+	storage := slices.DeleteFunc(slices.Clone(kindVersions), func(kv kindVersion) bool { return !kv.crd.Storage })
+
+	if len(storage) != 1 {
+		return kindVersion{}, fmt.Errorf("kind %v is defined in %v versions, of which %v are marked as storage. exactly 1 version must be marked as storage", kindVersions[0].kindName, len(kindVersions), len(storage))
+	}
+
+	return storage[0], nil
+}
+
+// plural returns the plural name of the specified kind, as set in its KindOptions or otherwise derived from the kind name
+func (crd CRDs) plural(kindName string) string {
+	if plural := crd.Options[kindName].Plural; plural != "" {
+		return plural
+	}
+
+	return pluralise(strings.ToLower(kindName))
+}
+
+func (s *ScaleOptions) subresource() *apiextensionsv1.CustomResourceSubresourceScale {
+	scale := &apiextensionsv1.CustomResourceSubresourceScale{
+		SpecReplicasPath:   s.SpecReplicasPath,
+		StatusReplicasPath: s.StatusReplicasPath,
+	}
+
+	if scale.SpecReplicasPath == "" {
+		scale.SpecReplicasPath = ".spec.replicas"
+	}
+
+	if scale.StatusReplicasPath == "" {
+		scale.StatusReplicasPath = ".status.replicas"
+	}
+
+	if s.LabelSelectorPath != "" {
+		scale.LabelSelectorPath = &s.LabelSelectorPath
+	}
+
+	return scale
+}
+
+// installCRDs creates, or updates where they already exist, the CustomResourceDefinitions of the cluster and waits for them to be established
+func (cluster *Cluster) installCRDs(ctx context.Context) error {
+	defer obs.MetricTimerFunc(ctx, "kapi_install_crds")()
+
+	klient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, false)
+
+	for crd := range slices.Values(cluster.crds) {
+		obs.LogFunc(ctx, 3, "installing custom resource definition", "crd", crd.Name)
+
+		// the generated ca bundle is set before the definition is written, so that conversions are not interrupted when it is updated
+		if conversion := crd.Spec.Conversion; conversion != nil && conversion.Webhook != nil && cluster.caBundle != nil {
+			conversion.Webhook.ClientConfig.CABundle = cluster.caBundle
+		}
+
+		existing, err := klient.Get(ctx, "", crd.Name)
+
+		switch {
+		case IsNotFound(err):
+			err = klient.Create(ctx, crd.DeepCopy())
+		case err == nil:
+			crd := crd.DeepCopy()
+			crd.ResourceVersion = existing.ResourceVersion
+			preserveCABundle(crd, existing)
+			err = klient.Update(ctx, crd)
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to install custom resource definition %v. %w", crd.Name, err)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		_, err = klient.WaitFor(waitCtx, "", crd.Name, CRDEstablished)
+		cancel()
+
+		if err != nil {
+			return fmt.Errorf("custom resource definition %v was not established. %w", crd.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// preserveCABundle retains the ca bundle of the existing conversion webhook where the definition does not set one, as where kapi does not
+// generate the webhook certificates it is typically injected by an external controller, such as the cert-manager ca-injector
+func preserveCABundle(crd, existing *apiextensionsv1.CustomResourceDefinition) {
+	conversion, existingConversion := crd.Spec.Conversion, existing.Spec.Conversion
+
+	if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil || len(conversion.Webhook.ClientConfig.CABundle) > 0 {
+		return
+	}
+
+	if existingConversion == nil || existingConversion.Webhook == nil || existingConversion.Webhook.ClientConfig == nil {
+		return
+	}
+
+	conversion.Webhook.ClientConfig.CABundle = existingConversion.Webhook.ClientConfig.CABundle
+}
+
+// pluralise returns the conventional english plural of the specified lowercase noun
+func pluralise(noun string) string {
+	switch {
+	case strings.HasSuffix(noun, "y") && len(noun) > 1 && !strings.ContainsAny(noun[len(noun)-2:len(noun)-1], "aeiou"):
+		return noun[:len(noun)-1] + "ies"
+	case strings.HasSuffix(noun, "s"), strings.HasSuffix(noun, "x"), strings.HasSuffix(noun, "z"), strings.HasSuffix(noun, "ch"), strings.HasSuffix(noun, "sh"):
+		return noun + "es"
+	default:
+		return noun + "s"
+	}
+}
@@ -0,0 +1,119 @@
+package kapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type dryRunKey struct{}
+
+// DryRun causes a write to be fully processed by the cluster, including admission control and validation, without being persisted.
+//
+// The resource passed to the write is updated to reflect the state it would have had if the write had been persisted
+func DryRun() Option {
+	return optionFunc(func(o *options) {
+		o.dryRun = true
+	})
+}
+
+// DryRunReconciler wraps the specified ReconcilerFunc so that every write made through a Client with the ctx passed to it is
+// executed as a dry-run; as if DryRun had been passed to each write. The change each write would have made is logged as a JSON merge patch.
+//
+// This allows new reconciler logic to be validated against a live cluster without it modifying any resources.
+// ClusterConfig.DryRunReconcilers applies the same behaviour to all reconcilers of a cluster.
+func DryRunReconciler[T client.Object](reconcilerFunc ReconcilerFunc[T]) ReconcilerFunc[T] {
+	return func(ctx context.Context, eventType ReconcileEventType, resource T) error {
+		return reconcilerFunc(withDryRun(ctx), eventType, resource)
+	}
+}
+
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func dryRunFrom(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// recordOrLogWrite records a persisted write for read-your-writes consistency or, for a dry-run, logs the change the write would have made
+func (c *Client[TItem, TList]) recordOrLogWrite(ctx context.Context, act Action, o options, original runtime.Object, resource TItem) {
+	if !o.dryRun {
+		c.recordWrite(resource, false)
+		return
+	}
+
+	c.logDryRun(ctx, act, resource.GetNamespace(), resource.GetName(), original, resource)
+}
+
+// current returns the current state of the specified resource on the cluster, or nil if it cannot be read. It is used as the
+// base from which the changes made by a dry-run write are determined
+func (c *Client[TItem, TList]) current(ctx context.Context, namespace, name string) runtime.Object {
+	resource := c.newResource()
+
+	if err := c.uncachedClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, resource); err != nil {
+		return nil
+	}
+
+	return resource
+}
+
+// logDryRun logs the change that a dry-run write would have made to a resource as a JSON merge patch from its original to its modified state.
+// A nil original indicates the resource would have been created and a nil modified indicates it would have been deleted
+func (c *Client[TItem, TList]) logDryRun(ctx context.Context, act Action, namespace, name string, original, modified runtime.Object) {
+	diff, err := dryRunDiff(original, modified)
+
+	if err != nil {
+		obs.LogFunc(ctx, 1, "kapi.client unable to determine dry-run diff", "error", err, "resource_action", act, "resource_type", c.resourceType, "resource_name", name, "resource_namespace", namespace)
+	}
+
+	obs.LogFunc(ctx, 2, "kapi.client dry-run", "type", "kapi_client_dry_run", "resource_action", act, "resource_type", c.resourceType, "resource_name", name, "resource_namespace", namespace, "diff", diff)
+}
+
+func dryRunDiff(original, modified runtime.Object) (string, error) {
+	originalJSON, err := dryRunJSON(original)
+
+	if err != nil {
+		return "", err
+	}
+
+	modifiedJSON, err := dryRunJSON(modified)
+
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := jsonpatch.CreateMergePatch(originalJSON, modifiedJSON)
+
+	if err != nil {
+		return "", fmt.Errorf("unable to create merge patch. %v", err)
+	}
+
+	return string(diff), nil
+}
+
+// dryRunJSON returns the JSON representation of a resource, without the metadata that changes on every write and so is not of interest in a diff
+func dryRunJSON(obj runtime.Object) ([]byte, error) {
+	if obj == nil {
+		return []byte("{}"), nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert resource to unstructured. %v", err)
+	}
+
+	if metadata, ok := u["metadata"].(map[string]any); ok {
+		delete(metadata, "managedFields")
+		delete(metadata, "resourceVersion")
+	}
+
+	return json.Marshal(u)
+}
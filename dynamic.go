@@ -0,0 +1,44 @@
+package kapi
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DynamicClientFor returns a Client that can be used to perform various IO operations against resources of the specified
+// GroupVersionKind, represented as unstructured.Unstructured.
+//
+// Unlike ClientFor, the kind does not need to be registered with the kapi.Cluster using a Go type. This allows resources
+// such as third-party CRDs to be used without vendoring their types. The kind is mapped to its k8s API resource using discovery.
+//
+// Caching and ClientOptions behave as described for ClientFor; the same Subresource, option and list semantics also apply.
+func DynamicClientFor(ctx context.Context, cluster *Cluster, gvk schema.GroupVersionKind, cache bool, opts ...ClientOption) *Client[*unstructured.Unstructured, *unstructured.UnstructuredList] {
+	return newClient(ctx, cluster, cache,
+		func() *unstructured.Unstructured {
+			resource := &unstructured.Unstructured{}
+			resource.SetGroupVersionKind(gvk)
+			return resource
+		},
+		func() *unstructured.UnstructuredList {
+			resourceList := &unstructured.UnstructuredList{}
+			resourceList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			return resourceList
+		},
+		gvk.String(),
+		gvk.GroupVersion().WithKind(gvk.Kind+"List").String(),
+		opts,
+	)
+}
+
+// AddDynamicReconciler causes the specified ReconcilerFunc to be invoked whenever any resource of the specified GroupVersionKind
+// in the specified cluster is subject to a modification event: create, update or delete.
+//
+// It behaves as AddReconciler, except that the kind does not need to be registered with the kapi.Cluster using a Go type and
+// resources are passed to the ReconcilerFunc as unstructured.Unstructured.
+func AddDynamicReconciler(ctx context.Context, cluster *Cluster, gvk schema.GroupVersionKind, reconcilerFilterFunc ReconcilerFilterFunc, reconcilerFunc ReconcilerFunc[*unstructured.Unstructured]) error {
+	klient := DynamicClientFor(ctx, cluster, gvk, true)
+
+	return addReconciler(ctx, cluster, klient.newResource(), klient.resourceType, klient.Get, reconcilerFilterFunc, reconcilerFunc)
+}
@@ -0,0 +1,191 @@
+package kapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type (
+	// InvalidError describes a request that was rejected by the k8s API server as invalid, along with the fields that caused the rejection.
+	//
+	// An InvalidError is obtained from an error returned by a Client using AsInvalidError
+	InvalidError struct {
+		// Message is the summary message returned by the k8s API server
+		Message string
+		// Causes describes each of the fields that caused the request to be rejected
+		Causes []FieldCause
+
+		err error
+	}
+	// FieldCause describes a field that caused a request to be rejected by the k8s API server
+	FieldCause struct {
+		// Field is the path of the field that caused the rejection; for example "spec.replicas"
+		Field string
+		// Type is the machine-readable type of the rejection; for example "FieldValueRequired"
+		Type string
+		// Message is a human-readable description of the rejection
+		Message string
+	}
+	// FieldErrors collects the fields of a resource that are invalid, so that they can be reported together.
+	//
+	// Returned from a Hook func with Err, the collected errors are reported by the k8s API server as an Invalid error with a cause for each field,
+	// in the same way as violations of `kapi` tag and CEL rules; this allows clients, such as kubectl, to identify the offending fields.
+	// The zero value is ready to use
+	FieldErrors struct {
+		errs field.ErrorList
+	}
+)
+
+// IsNotFound returns true if the error, or any error it wraps, indicates that the requested resource does not exist
+func IsNotFound(err error) bool {
+	return apierrors.IsNotFound(err)
+}
+
+// IsConflict returns true if the error, or any error it wraps, indicates that the request conflicted with the current state of
+// the resource; typically because the resource was modified after it was read
+func IsConflict(err error) bool {
+	return apierrors.IsConflict(err)
+}
+
+// IsAlreadyExists returns true if the error, or any error it wraps, indicates that the resource being created already exists
+func IsAlreadyExists(err error) bool {
+	return apierrors.IsAlreadyExists(err)
+}
+
+// IsForbidden returns true if the error, or any error it wraps, indicates that the request was not permitted
+func IsForbidden(err error) bool {
+	return apierrors.IsForbidden(err)
+}
+
+// IsInvalid returns true if the error, or any error it wraps, indicates that the request was rejected as invalid.
+// Details of the invalid fields can be obtained with AsInvalidError
+func IsInvalid(err error) bool {
+	return apierrors.IsInvalid(err)
+}
+
+// IsTimeout returns true if the error, or any error it wraps, indicates that the request, or the k8s API server processing it, timed out
+func IsTimeout(err error) bool {
+	return apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err)
+}
+
+// AsInvalidError returns an InvalidError describing the fields that caused a request to be rejected as invalid.
+//
+// If the error, or any error it wraps, does not indicate an invalid request, false is returned
+func AsInvalidError(err error) (*InvalidError, bool) {
+	var (
+		invalidErr *InvalidError
+		statusErr  apierrors.APIStatus
+	)
+
+	if errors.As(err, &invalidErr) {
+		return invalidErr, true
+	}
+
+	if !errors.As(err, &statusErr) || !apierrors.IsInvalid(err) {
+		return nil, false
+	}
+
+	status := statusErr.Status()
+
+	invalidErr = &InvalidError{
+		Message: status.Message,
+		err:     err,
+	}
+
+	if status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			invalidErr.Causes = append(invalidErr.Causes, FieldCause{
+				Field:   cause.Field,
+				Type:    string(cause.Type),
+				Message: cause.Message,
+			})
+		}
+	}
+
+	return invalidErr, true
+}
+
+func (e *InvalidError) Error() string {
+	if len(e.Causes) == 0 {
+		return e.Message
+	}
+
+	causes := make([]string, 0, len(e.Causes))
+
+	for _, cause := range e.Causes {
+		causes = append(causes, fmt.Sprintf("%v: %v", cause.Field, cause.Message))
+	}
+
+	return fmt.Sprintf("%v. causes: [%v]", e.Message, strings.Join(causes, ", "))
+}
+
+func (e *InvalidError) Unwrap() error {
+	return e.err
+}
+
+// Required records that the field at path, such as "spec.tier", requires a value
+func (e *FieldErrors) Required(path, detail string) {
+	e.errs = append(e.errs, &field.Error{Type: field.ErrorTypeRequired, Field: path, BadValue: "", Detail: detail})
+}
+
+// Invalid records that the field at path, such as "spec.items[0].key", has an invalid value
+func (e *FieldErrors) Invalid(path string, value any, detail string) {
+	e.errs = append(e.errs, &field.Error{Type: field.ErrorTypeInvalid, Field: path, BadValue: value, Detail: detail})
+}
+
+// NotSupported records that the field at path has a value that is not one of the supported values
+func (e *FieldErrors) NotSupported(path string, value any, supported ...string) {
+	e.errs = append(e.errs, field.NotSupported(field.NewPath(path), value, supported))
+}
+
+// Forbidden records that the field at path may not be set; for example by the requesting user or in the current state of the resource
+func (e *FieldErrors) Forbidden(path, detail string) {
+	e.errs = append(e.errs, &field.Error{Type: field.ErrorTypeForbidden, Field: path, BadValue: "", Detail: detail})
+}
+
+// Duplicate records that the field at path has a value that duplicates that of another field, such as an item of a list
+func (e *FieldErrors) Duplicate(path string, value any) {
+	e.errs = append(e.errs, &field.Error{Type: field.ErrorTypeDuplicate, Field: path, BadValue: value})
+}
+
+// Add records field errors created with the k8s field package, such as those returned by ValidateCEL
+func (e *FieldErrors) Add(errs ...*field.Error) {
+	e.errs = append(e.errs, errs...)
+}
+
+// Len returns the number of field errors recorded
+func (e *FieldErrors) Len() int {
+	if e == nil {
+		return 0
+	}
+
+	return len(e.errs)
+}
+
+// Err returns the FieldErrors as an error, or nil where none have been recorded
+func (e *FieldErrors) Err() error {
+	if e.Len() == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// StatusError returns the k8s Invalid error describing the recorded field errors, for a resource of the specified kind and name, or nil where none have
+// been recorded. A Hook does this for FieldErrors returned by its funcs, so this is typically only required outside of hooks
+func (e *FieldErrors) StatusError(groupKind schema.GroupKind, name string) error {
+	if e.Len() == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(groupKind, name, e.errs)
+}
+
+func (e *FieldErrors) Error() string {
+	return e.errs.ToAggregate().Error()
+}
@@ -2,12 +2,16 @@ package kapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -19,11 +23,22 @@ import (
 // - ValidateDeleteFunc: Validates resources before deletion
 // - DefaulterFunc: Sets default values for new resources
 // Any of these functions can be omitted (left as nil) if the desired behavior is not required.
+//
+// The defaults and validation rules declared in the `kapi` tags of the resource's fields, and any CEL rules declared for its types, are applied
+// before the functions are invoked; resources that violate the rules are rejected with an Invalid error that identifies each offending field.
+// The functions can report invalid fields in the same way by returning the Err of a FieldErrors.
+//
+// Details of the request being admitted, such as the requesting user and whether it is a dry-run, are obtained from the ctx passed to the
+// functions with AdmissionRequestFrom.
 type Hook[T client.Object] struct {
 	DefaulterFunc      func(ctx context.Context, resource T) error
 	ValidateCreateFunc func(ctx context.Context, resource T) (warnings []string, err error)
 	ValidateUpdateFunc func(ctx context.Context, oldResource, newResource T) (warnings []string, err error)
 	ValidateDeleteFunc func(ctx context.Context, resource T) (warnings []string, err error)
+	// Options defines how the API server invokes the hook, where ClusterConfig.Webhooks.InstallConfigurations is set
+	Options   HookOptions
+	groupKind schema.GroupKind
+	schema    *apiextensionsv1.JSONSchemaProps
 }
 
 // AddHook registers a hook with the provided cluster.
@@ -41,6 +56,37 @@ func AddHook[T client.Object](ctx context.Context, cluster *Cluster, hook *Hook[
 
 	t := reflect.New(reflect.TypeOf(zeroOfT).Elem()).Interface().(T)
 
+	if err := validateTags(reflect.TypeOf(t)); err != nil {
+		return fmt.Errorf("unable to add hook for %T. %v", zeroOfT, err)
+	}
+
+	gvk, err := apiutil.GVKForObject(t, cluster.manager.GetScheme())
+
+	if err != nil {
+		return fmt.Errorf("unable to add hook for %T. %v", zeroOfT, err)
+	}
+
+	hook.groupKind = gvk.GroupKind()
+
+	// a schema is only required to evaluate cel rules, so where none are declared a type that cannot be described by one is still supported
+	if schema, err := schemaFor(reflect.TypeOf(t)); err != nil && declaresCELRules(reflect.TypeOf(t)) {
+		return fmt.Errorf("unable to add hook for %T. cel rules are declared but a schema could not be generated to evaluate them. %v", zeroOfT, err)
+	} else if err != nil {
+		obs.LogFunc(ctx, 1, "schema could not be generated for kapi.hook. no cel rules are declared so none will be evaluated", "resource_type", fmt.Sprintf("%T", zeroOfT), "error", err.Error())
+	} else if err := compileCELRules(&schema); err != nil {
+		return fmt.Errorf("unable to add hook for %T. %v", zeroOfT, err)
+	} else {
+		hook.schema = &schema
+	}
+
+	registration, err := hook.registration(gvk, t)
+
+	if err != nil {
+		return fmt.Errorf("unable to add hook for %T. %v", zeroOfT, err)
+	}
+
+	cluster.hooks = append(cluster.hooks, registration)
+
 	ctrl.NewWebhookManagedBy(cluster.manager).
 		For(t).
 		WithValidator(hook).
@@ -51,10 +97,6 @@ func AddHook[T client.Object](ctx context.Context, cluster *Cluster, hook *Hook[
 }
 
 func (h *Hook[T]) Default(ctx context.Context, obj runtime.Object) error {
-	if h.DefaulterFunc == nil {
-		return nil
-	}
-
 	defer h.observe(ctx, "default", obj)()
 
 	resource, ok := obj.(T)
@@ -63,14 +105,18 @@ func (h *Hook[T]) Default(ctx context.Context, obj runtime.Object) error {
 		return fmt.Errorf("defaulter for custom resource of %T was passed type of %T", h, obj)
 	}
 
-	return h.DefaulterFunc(ctx, resource)
-}
+	if err := applyTagDefaults(resource); err != nil {
+		return fmt.Errorf("unable to apply defaults to %T. %v", resource, err)
+	}
 
-func (h *Hook[T]) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	if h.ValidateCreateFunc == nil {
-		return nil, nil
+	if h.DefaulterFunc == nil {
+		return nil
 	}
 
+	return h.statusError(resource, h.DefaulterFunc(ctx, resource))
+}
+
+func (h *Hook[T]) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	defer h.observe(ctx, "create", obj)()
 
 	resource, ok := obj.(T)
@@ -79,14 +125,20 @@ func (h *Hook[T]) ValidateCreate(ctx context.Context, obj runtime.Object) (admis
 		return nil, fmt.Errorf("creation validator for custom resource of %T was passed type of %T", h, obj)
 	}
 
-	return h.ValidateCreateFunc(ctx, resource)
-}
+	if err := h.validateRules(nil, resource); err != nil {
+		return nil, err
+	}
 
-func (h *Hook[T]) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
-	if h.ValidateUpdateFunc == nil {
+	if h.ValidateCreateFunc == nil {
 		return nil, nil
 	}
 
+	warnings, err := h.ValidateCreateFunc(ctx, resource)
+
+	return warnings, h.statusError(resource, err)
+}
+
+func (h *Hook[T]) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
 	defer h.observe(ctx, "update", newObj)()
 
 	newResource, okNew := newObj.(T)
@@ -96,7 +148,17 @@ func (h *Hook[T]) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Obj
 		return nil, fmt.Errorf("update validator for custom resource of %T was passed types of %T and %T", h, newObj, oldObj)
 	}
 
-	return h.ValidateUpdateFunc(ctx, oldResource, newResource)
+	if err := h.validateRules(oldResource, newResource); err != nil {
+		return nil, err
+	}
+
+	if h.ValidateUpdateFunc == nil {
+		return nil, nil
+	}
+
+	warnings, err := h.ValidateUpdateFunc(ctx, oldResource, newResource)
+
+	return warnings, h.statusError(newResource, err)
 }
 
 func (h *Hook[T]) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
@@ -112,7 +174,40 @@ func (h *Hook[T]) ValidateDelete(ctx context.Context, obj runtime.Object) (admis
 		return nil, fmt.Errorf("deletion validator for custom resource of %T was passed type of %T", h, obj)
 	}
 
-	return h.ValidateDeleteFunc(ctx, resource)
+	warnings, err := h.ValidateDeleteFunc(ctx, resource)
+
+	return warnings, h.statusError(resource, err)
+}
+
+// validateRules returns an Invalid error describing the violations of the kapi tag rules and CEL rules of the passed resource, if any.
+// oldResource is nil on creation
+func (h *Hook[T]) validateRules(oldResource client.Object, resource T) error {
+	errs := &FieldErrors{}
+	errs.Add(validateTagRules(resource)...)
+
+	if h.schema != nil {
+		celErrs, err := validateCELRules(h.schema, oldResource, resource)
+
+		if err != nil {
+			return fmt.Errorf("unable to evaluate cel rules for %T. %v", resource, err)
+		}
+
+		errs.Add(celErrs...)
+	}
+
+	return errs.StatusError(h.groupKind, resource.GetName())
+}
+
+// statusError returns the Invalid error describing any FieldErrors returned by a Hook func, so that they are reported in the same way as the
+// violations of tag and CEL rules. Other errors are returned as they are
+func (h *Hook[T]) statusError(resource T, err error) error {
+	var fieldErrs *FieldErrors
+
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs.StatusError(h.groupKind, resource.GetName())
+	}
+
+	return err
 }
 
 func (h *Hook[T]) observe(ctx context.Context, act string, obj runtime.Object) func() {
@@ -0,0 +1,68 @@
+package kapi
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// As returns a copy of the Client that performs all operations as the specified user and groups, using k8s impersonation.
+// This allows resources to be accessed on behalf of end users, subject to their RBAC permissions rather than those of the controller.
+//
+// As the cache is populated using the controller's identity, the returned Client is always uncached. The controller's own identity must
+// be permitted to impersonate the specified user and groups.
+//
+// The impersonated identity is included in the Client's logs and metrics and is available to Interceptors in the Operation
+func (c *Client[TItem, TList]) As(user string, groups ...string) (*Client[TItem, TList], error) {
+	cfg := rest.CopyConfig(c.cluster.manager.GetConfig())
+	cfg.Impersonate = rest.ImpersonationConfig{
+		UserName: user,
+		Groups:   groups,
+	}
+
+	// client-go caches the underlying transports by their tls configuration, so the http client created for each impersonated client
+	// shares connections with the cluster's own clients
+	httpClient, err := rest.HTTPClientFor(cfg)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create http client for impersonated kapi.client. %v", err)
+	}
+
+	uncachedClient, err := client.NewWithWatch(cfg, client.Options{
+		Scheme:     c.cluster.manager.GetScheme(),
+		Mapper:     c.cluster.manager.GetRESTMapper(),
+		HTTPClient: httpClient,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create impersonated kapi.client. %v", err)
+	}
+
+	impersonated := *c
+	impersonated.uncachedClient = uncachedClient
+	impersonated.cached = false
+	impersonated.impersonate = cfg.Impersonate
+
+	return &impersonated, nil
+}
+
+// identity returns the impersonated identity of the client as observability attributes, or no attributes if the client is not impersonating
+func (c *Client[TItem, TList]) identity() []any {
+	if c.impersonate.UserName == "" {
+		return nil
+	}
+
+	return []any{"impersonated_user", c.impersonate.UserName, "impersonated_groups", c.impersonate.Groups}
+}
+
+// identityAttributes returns the impersonated identity of the client as metric attributes, or no attributes if the client is not impersonating
+func (c *Client[TItem, TList]) identityAttributes() []string {
+	if c.impersonate.UserName == "" {
+		return nil
+	}
+
+	return []string{"impersonated_user", c.impersonate.UserName, "impersonated_groups", strings.Join(slices.Sorted(slices.Values(c.impersonate.Groups)), ",")}
+}
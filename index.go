@@ -0,0 +1,165 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AddIndex registers a named index for resources of type T in the cache of the specified cluster. The indexFunc is
+// invoked for each cached resource and returns the values under which the resource is indexed.
+//
+// For example, the below indexes ConfigAudit resources by the name of the ConfigMap they reference.
+//
+//	kapi.AddIndex(ctx, cluster, "configmap", func(c *ConfigAudit) []string {
+//		return []string{c.Spec.ConfigMapName}
+//	})
+//
+// Indexes are queried using Client.ListByIndex and WatchByIndex. AddIndex must be called before kapi.Cluster.Connect
+func AddIndex[T client.Object](ctx context.Context, cluster *Cluster, name string, indexFunc func(T) []string) error {
+	if cluster.connected {
+		panic("kapi.add-index must be called before kapi.cluster.connect")
+	}
+
+	var zeroOfT T
+
+	defer obs.MetricTimerFunc(ctx, "kapi_add_index")("resource_type", fmt.Sprintf("%T", zeroOfT), "index", name)
+	obs.LogFunc(ctx, 3, "creating kapi.index", "resource_type", fmt.Sprintf("%T", zeroOfT), "index", name)
+
+	resource := reflect.New(reflect.TypeOf(zeroOfT).Elem()).Interface().(T)
+
+	err := cluster.manager.GetFieldIndexer().IndexField(ctx, resource, name, func(obj client.Object) []string {
+		resource, ok := obj.(T)
+
+		if !ok {
+			return nil
+		}
+
+		return indexFunc(resource)
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to configure kapi.index %v. %v", name, err)
+	}
+
+	return nil
+}
+
+// ListByIndex returns data describing all occurences of the resource type associated with the client that are indexed
+// under the specified value by the named index. Indexes are registered with AddIndex.
+//
+// As indexes are maintained in the cache, ListByIndex is only supported by cached clients. It is not read-your-writes consistent, even where
+// the client is created with WithReadYourWrites
+func (c *Client[TItem, TList]) ListByIndex(ctx context.Context, name, value string) (TList, error) {
+	resourceList := c.newResourceList()
+
+	return resourceList, c.intercept(ctx, ActionListByIndex, "", "", resourceList, func(ctx context.Context) error {
+		if !c.cached {
+			return fmt.Errorf("kapi.client.list-by-index requires a cached kapi.client")
+		}
+
+		clt, err := c.getClient(false)
+
+		if err != nil {
+			return err
+		}
+
+		if c.listErr != nil {
+			return c.listErr
+		}
+
+		return clt.List(ctx, resourceList, client.MatchingFields{name: value})
+	})
+}
+
+// WatchByIndex causes the reconciler for resources of type T to be invoked whenever a resource of type TWatched is created,
+// updated or deleted. The reconciler is invoked once for each resource of type T that is indexed by the named index under the
+// value returned by keyFunc for the modified TWatched resource.
+//
+// For example, the below invokes the ConfigAudit reconciler for each ConfigAudit that references a modified ConfigMap.
+//
+//	kapi.WatchByIndex(ctx, cluster, "configmap", func(c *corev1.ConfigMap) string {
+//		return c.Name
+//	})
+//
+// The reconciler for T must have been added with AddReconciler and the index registered with AddIndex. WatchByIndex must be
+// called before kapi.Cluster.Connect
+func WatchByIndex[T client.Object, TWatched client.Object](ctx context.Context, cluster *Cluster, index string, keyFunc func(TWatched) string) error {
+	if cluster.connected {
+		panic("kapi.watch-by-index must be called before kapi.cluster.connect")
+	}
+
+	var (
+		zeroOfT        T
+		zeroOfTWatched TWatched
+	)
+
+	defer obs.MetricTimerFunc(ctx, "kapi_watch_by_index")("resource_type", fmt.Sprintf("%T", zeroOfT), "watched_resource_type", fmt.Sprintf("%T", zeroOfTWatched), "index", index)
+
+	scheme := cluster.manager.GetScheme()
+	resource := reflect.New(reflect.TypeOf(zeroOfT).Elem()).Interface().(T)
+
+	gvk, err := apiutil.GVKForObject(resource, scheme)
+
+	if err != nil {
+		return fmt.Errorf("unable to determine kind of resource type %T. %v", resource, err)
+	}
+
+	ctrlr, ok := cluster.controllers[gvk]
+
+	if !ok {
+		return fmt.Errorf("no kapi.reconciler has been added for resource type %T", resource)
+	}
+
+	newResourceList := func() (client.ObjectList, error) {
+		resourceList, err := scheme.New(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to create list for resource type %T. %v", resource, err)
+		}
+
+		return resourceList.(client.ObjectList), nil
+	}
+
+	if _, err := newResourceList(); err != nil {
+		return err
+	}
+
+	mapFunc := func(ctx context.Context, watched TWatched) []reconcile.Request {
+		resourceList, _ := newResourceList()
+
+		if err := cluster.manager.GetClient().List(ctx, resourceList, client.MatchingFields{index: keyFunc(watched)}); err != nil {
+			obs.LogFunc(ctx, 0, "kapi.watch-by-index unable to list indexed resources", "error", err, "resource_type", fmt.Sprintf("%T", resource), "index", index)
+			return nil
+		}
+
+		items, _ := meta.ExtractList(resourceList)
+		requests := make([]reconcile.Request, 0, len(items))
+
+		for _, item := range items {
+			if obj, ok := item.(client.Object); ok {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}})
+			}
+		}
+
+		obs.LogFunc(ctx, 3, "kapi.watch-by-index mapped event", "resource_type", fmt.Sprintf("%T", resource), "watched_resource_name", watched.GetName(), "index", index, "requests", len(requests))
+
+		return requests
+	}
+
+	watched := reflect.New(reflect.TypeOf(zeroOfTWatched).Elem()).Interface().(TWatched)
+
+	if err := ctrlr.Watch(source.Kind(cluster.manager.GetCache(), watched, handler.TypedEnqueueRequestsFromMapFunc(mapFunc))); err != nil {
+		return fmt.Errorf("unable to configure kapi.watch-by-index. %v", err)
+	}
+
+	return nil
+}
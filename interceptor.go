@@ -0,0 +1,146 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// Action identifies the type of Client operation being performed
+	Action string
+	// Operation describes a Client operation as it is passed through a chain of Interceptors
+	Operation struct {
+		// Action is the type of operation being performed
+		Action Action
+		// ResourceType is the type of resource the Client operates on
+		ResourceType string
+		// Namespace and Name identify the resource being operated on. Either may be empty where the operation is not limited to a namespace or a single resource
+		Namespace string
+		Name      string
+		// Resource is the resource being written, the resource or resource list being read into or nil where the operation has no associated resource.
+		//
+		// Interceptors can modify the Resource of a write before invoking next; for example to add standard labels. Reads are
+		// populated once next has returned.
+		Resource runtime.Object
+		// User and Groups identify who the operation is performed as, where the Client was created with Client.As. They are empty otherwise
+		User   string
+		Groups []string
+	}
+	// Interceptor wraps a Client operation, allowing it to be inspected, modified, rejected or observed.
+	//
+	// An Interceptor must invoke next to continue the operation; either with the passed ctx or one derived from it. Returning without
+	// invoking next prevents the operation from executing.
+	Interceptor func(ctx context.Context, op Operation, next func(ctx context.Context) error) error
+)
+
+const (
+	ActionCreate      Action = "create"
+	ActionUpdate      Action = "update"
+	ActionPatch       Action = "patch"
+	ActionDelete      Action = "delete"
+	ActionDeleteAllOf Action = "delete_all_of"
+	ActionGet         Action = "get"
+	ActionList        Action = "list"
+	ActionListByIndex Action = "list_by_index"
+)
+
+// WithInterceptors adds the specified Interceptors to the Client. They are invoked, in the order passed, after any Interceptors
+// configured in the ClusterConfig
+func WithInterceptors(interceptors ...Interceptor) ClientOption {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// NamespaceInterceptor returns an Interceptor that rejects writes to resources outside of the specified namespaces with a forbidden error.
+//
+// Writes to cluster-scoped resources and deletes that are not limited to a namespace are also rejected.
+func NamespaceInterceptor(namespaces ...string) Interceptor {
+	return func(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+		if !op.Action.IsWrite() || slices.Contains(namespaces, op.Namespace) {
+			return next(ctx)
+		}
+
+		obs.LogFunc(ctx, 1, "kapi.namespace-interceptor rejected write", "resource_action", op.Action, "resource_type", op.ResourceType, "resource_name", op.Name, "resource_namespace", op.Namespace)
+
+		return apierrors.NewForbidden(schema.GroupResource{Resource: op.ResourceType}, op.Name, fmt.Errorf("writes to namespace %q are not permitted, permitted namespaces are %v", op.Namespace, namespaces))
+	}
+}
+
+// LabelInterceptor returns an Interceptor that sets the specified labels on all resources that are created, updated or patched.
+// Existing labels with the same keys are overwritten
+func LabelInterceptor(labels map[string]string) Interceptor {
+	return func(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+		if resource, ok := op.Resource.(client.Object); ok && (op.Action == ActionCreate || op.Action == ActionUpdate || op.Action == ActionPatch) {
+			resourceLabels := resource.GetLabels()
+
+			if resourceLabels == nil {
+				resourceLabels = make(map[string]string, len(labels))
+			}
+
+			maps.Copy(resourceLabels, labels)
+			resource.SetLabels(resourceLabels)
+		}
+
+		return next(ctx)
+	}
+}
+
+// IsWrite returns true if the Action modifies resources on the cluster
+func (a Action) IsWrite() bool {
+	switch a {
+	case ActionGet, ActionList, ActionListByIndex:
+		return false
+	default:
+		return true
+	}
+}
+
+// intercept executes fn as the final step of the Client's chain of Interceptors, preceded by the built-in observeInterceptor
+func (c *Client[TItem, TList]) intercept(ctx context.Context, action Action, namespace, name string, resource runtime.Object, fn func(ctx context.Context) error) error {
+	op := Operation{
+		Action:       action,
+		ResourceType: c.resourceType,
+		Namespace:    namespace,
+		Name:         name,
+		Resource:     resource,
+		User:         c.impersonate.UserName,
+		Groups:       c.impersonate.Groups,
+	}
+
+	invoke := fn
+
+	for _, interceptor := range slices.Backward(slices.Concat([]Interceptor{c.observeInterceptor}, c.interceptors)) {
+		next := invoke
+		invoke = func(ctx context.Context) error {
+			return interceptor(ctx, op, next)
+		}
+	}
+
+	return invoke(ctx)
+}
+
+// observeInterceptor precedes the Interceptors in the chain of every Client and provides the built-in logging and metrics
+func (c *Client[TItem, TList]) observeInterceptor(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+	defer c.observe(ctx, string(op.Action), op.Resource)()
+
+	return next(ctx)
+}
+
+func (c *Client[TItem, TList]) observe(ctx context.Context, act string, obj runtime.Object) func() {
+	stopTimer := obs.MetricTimerFunc(ctx, "kapi_client")
+
+	obs.LogFunc(ctx, 1, "kapi.client invoked", append([]any{"type", "kapi_client_summary", "resource_action", act, "resource_type", c.resourceType, "resource_list_type", c.resourceListType}, c.identity()...)...)
+
+	return func() {
+		obs.LogFunc(ctx, 3, "kapi.client invoked", append([]any{"type", "kapi_client_trace", "resource_action", act, "resource_type", c.resourceType, "resource_list_type", c.resourceListType, "resource", fmt.Sprintf("+%v", obj)}, c.identity()...)...)
+		stopTimer(append([]string{"resource_type", c.resourceType, "resource_action", act}, c.identityAttributes()...)...)
+	}
+}
@@ -0,0 +1,482 @@
+// certs provides self-signed TLS certificates for the webhook server of a controller or operator
+//
+// A CA and a serving certificate for the webhook Service are generated and stored in a Secret, so that all replicas of a high availability
+// deployment serve the same certificate. The certificates are rotated once two thirds of their validity has elapsed; the previous CA is retained
+// in the CA bundle until it expires, so that clients trusting either CA accept the serving certificate during rotation.
+//
+// As a rotated serving certificate is issued by the new CA, the CA bundle must be injected with InjectCABundle before the certificate is served
+// with Write; otherwise clients cannot verify it until the injection completes.
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// Config defines the Service for which certificates are generated and the Secret in which they are stored
+	Config struct {
+		ServiceName      string
+		ServiceNamespace string
+		// SecretName defines the name of the Secret, which is created in the ServiceNamespace
+		SecretName string
+		// Validity defines how long generated certificates are valid for
+		Validity time.Duration
+		// Now returns the current time. By default, this is time.Now
+		Now func() time.Time
+	}
+	// Bundle defines a serving certificate and the CAs that clients should trust to verify it
+	Bundle struct {
+		// CABundle is the PEM encoded current CA followed by the previous CA, where it has not expired
+		CABundle []byte
+		// Cert and Key are the PEM encoded serving certificate and its private key
+		Cert, Key []byte
+		// NotBefore and NotAfter define the validity of the serving certificate
+		NotBefore, NotAfter time.Time
+
+		caKey []byte
+	}
+)
+
+const (
+	// CertFile and KeyFile are the names of the files to which the serving certificate and key are written, as expected by the ctrl-runtime webhook server
+	CertFile = "tls.crt"
+	KeyFile  = "tls.key"
+
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+
+	// maxAttempts is the number of times the Secret is read and written where it is concurrently modified by another replica
+	maxAttempts = 5
+)
+
+// DNSNames returns the DNS names by which the Service is addressed within the cluster
+func (cfg Config) DNSNames() []string {
+	return []string{
+		cfg.ServiceName,
+		cfg.ServiceName + "." + cfg.ServiceNamespace,
+		cfg.ServiceName + "." + cfg.ServiceNamespace + ".svc",
+		cfg.ServiceName + "." + cfg.ServiceNamespace + ".svc.cluster.local",
+	}
+}
+
+func (cfg Config) now() time.Time {
+	if cfg.Now == nil {
+		return time.Now()
+	}
+
+	return cfg.Now()
+}
+
+// RenewAt returns the time at which the serving certificate of the Bundle is rotated; once two thirds of its validity has elapsed
+func (b Bundle) RenewAt() time.Time {
+	return b.NotBefore.Add(b.NotAfter.Sub(b.NotBefore) * 2 / 3)
+}
+
+// Ensure returns the Bundle stored in the Secret defined by the Config, generating it, or rotating it, where required.
+//
+// Where the Secret is concurrently created or updated by another replica, the Bundle written by that replica is returned
+func Ensure(ctx context.Context, c client.Client, cfg Config) (Bundle, error) {
+	for range maxAttempts {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, client.ObjectKey{Namespace: cfg.ServiceNamespace, Name: cfg.SecretName}, secret)
+
+		switch {
+		case apierrors.IsNotFound(err):
+			bundle, err := Generate(cfg, nil)
+
+			if err != nil {
+				return Bundle{}, err
+			}
+
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: cfg.ServiceNamespace, Name: cfg.SecretName},
+				Type:       corev1.SecretTypeTLS,
+				Data:       bundle.data(),
+			}
+
+			if err := c.Create(ctx, secret); apierrors.IsAlreadyExists(err) {
+				continue
+			} else if err != nil {
+				return Bundle{}, fmt.Errorf("unable to create secret %v/%v. %w", cfg.ServiceNamespace, cfg.SecretName, err)
+			}
+
+			return bundle, nil
+		case err != nil:
+			return Bundle{}, fmt.Errorf("unable to get secret %v/%v. %w", cfg.ServiceNamespace, cfg.SecretName, err)
+		}
+
+		bundle, err := bundleOf(secret)
+
+		if err == nil && !bundle.needsRotation(cfg) {
+			return bundle, nil
+		}
+
+		var previous *Bundle
+
+		if err == nil {
+			previous = &bundle
+		}
+
+		if bundle, err = Generate(cfg, previous); err != nil {
+			return Bundle{}, err
+		}
+
+		secret.Data = bundle.data()
+
+		if err := c.Update(ctx, secret); apierrors.IsConflict(err) {
+			continue
+		} else if err != nil {
+			return Bundle{}, fmt.Errorf("unable to update secret %v/%v. %w", cfg.ServiceNamespace, cfg.SecretName, err)
+		}
+
+		return bundle, nil
+	}
+
+	return Bundle{}, fmt.Errorf("unable to ensure secret %v/%v after %v attempts as it was concurrently modified", cfg.ServiceNamespace, cfg.SecretName, maxAttempts)
+}
+
+// Generate returns a Bundle with a new CA and serving certificate. Where a previous Bundle is passed, its current CA is retained in the CA bundle
+// until it expires
+func Generate(cfg Config, previous *Bundle) (Bundle, error) {
+	now := cfg.now()
+	// certificate validity is encoded to the second
+	notBefore, notAfter := now.Add(-time.Minute).UTC().Truncate(time.Second), now.Add(cfg.Validity).UTC().Truncate(time.Second)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to generate ca key. %v", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: cfg.ServiceName + "-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	if caTemplate.SerialNumber, err = serialNumber(); err != nil {
+		return Bundle{}, err
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to create ca certificate. %v", err)
+	}
+
+	ca, err := x509.ParseCertificate(caDER)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to parse ca certificate. %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to generate serving key. %v", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cfg.DNSNames()[2]},
+		DNSNames:    cfg.DNSNames(),
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	if template.SerialNumber, err = serialNumber(); err != nil {
+		return Bundle{}, err
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to create serving certificate. %v", err)
+	}
+
+	caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to encode ca key. %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+
+	if err != nil {
+		return Bundle{}, fmt.Errorf("unable to encode serving key. %v", err)
+	}
+
+	bundle := Bundle{
+		CABundle:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		Cert:      pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:       pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+		caKey:     pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER}),
+	}
+
+	if previous != nil {
+		if previousCA, err := firstCertificate(previous.CABundle); err == nil && now.Before(previousCA.NotAfter) {
+			bundle.CABundle = append(bundle.CABundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: previousCA.Raw})...)
+		}
+	}
+
+	return bundle, nil
+}
+
+// Write writes the serving certificate and key of the Bundle to the specified directory, returning true if either has changed.
+//
+// Each file is written to a temporary file that is then renamed, so that a partially written file is never read
+func Write(dir string, b Bundle) (bool, error) {
+	changed := false
+
+	for file := range slices.Values([]struct {
+		name string
+		data []byte
+	}{{KeyFile, b.Key}, {CertFile, b.Cert}}) {
+		path, data := filepath.Join(dir, file.name), file.data
+
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+
+		tmp := path + ".tmp"
+
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return changed, fmt.Errorf("unable to write %v. %v", tmp, err)
+		}
+
+		if err := os.Rename(tmp, path); err != nil {
+			return changed, fmt.Errorf("unable to rename %v to %v. %v", tmp, path, err)
+		}
+
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// InjectCABundle sets the CA bundle of each webhook, in the Validating and Mutating webhook configurations, and each CustomResourceDefinition
+// conversion webhook that references the Service defined by the Config. It returns the names of the patched resources
+func InjectCABundle(ctx context.Context, c client.Client, cfg Config, caBundle []byte) ([]string, error) {
+	patched := []string{}
+
+	references := func(service *admissionregistrationv1.ServiceReference) bool {
+		return service != nil && service.Name == cfg.ServiceName && service.Namespace == cfg.ServiceNamespace
+	}
+
+	patch := func(obj client.Object, inject func() bool) error {
+		original := obj.DeepCopyObject().(client.Object)
+
+		if !inject() {
+			return nil
+		}
+
+		if err := c.Patch(ctx, obj, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+			return fmt.Errorf("unable to patch ca bundle of %T %v. %w", obj, obj.GetName(), err)
+		}
+
+		patched = append(patched, obj.GetName())
+
+		return nil
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+
+	if err := c.List(ctx, validating); err != nil {
+		return patched, fmt.Errorf("unable to list validating webhook configurations. %w", err)
+	}
+
+	for i := range validating.Items {
+		configuration := &validating.Items[i]
+
+		err := patch(configuration, func() bool {
+			return injectWebhooks(configuration.Webhooks, func(w *admissionregistrationv1.ValidatingWebhook) *admissionregistrationv1.WebhookClientConfig {
+				return &w.ClientConfig
+			}, references, caBundle)
+		})
+
+		if err != nil {
+			return patched, err
+		}
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+
+	if err := c.List(ctx, mutating); err != nil {
+		return patched, fmt.Errorf("unable to list mutating webhook configurations. %w", err)
+	}
+
+	for i := range mutating.Items {
+		configuration := &mutating.Items[i]
+
+		err := patch(configuration, func() bool {
+			return injectWebhooks(configuration.Webhooks, func(w *admissionregistrationv1.MutatingWebhook) *admissionregistrationv1.WebhookClientConfig {
+				return &w.ClientConfig
+			}, references, caBundle)
+		})
+
+		if err != nil {
+			return patched, err
+		}
+	}
+
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+
+	if err := c.List(ctx, crds); err != nil {
+		return patched, fmt.Errorf("unable to list custom resource definitions. %w", err)
+	}
+
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+
+		err := patch(crd, func() bool {
+			if crd.Spec.Conversion == nil || crd.Spec.Conversion.Webhook == nil || crd.Spec.Conversion.Webhook.ClientConfig == nil {
+				return false
+			}
+
+			clientConfig := crd.Spec.Conversion.Webhook.ClientConfig
+
+			if clientConfig.Service == nil || clientConfig.Service.Name != cfg.ServiceName || clientConfig.Service.Namespace != cfg.ServiceNamespace {
+				return false
+			}
+
+			if bytes.Equal(clientConfig.CABundle, caBundle) {
+				return false
+			}
+
+			clientConfig.CABundle = caBundle
+
+			return true
+		})
+
+		if err != nil {
+			return patched, err
+		}
+	}
+
+	return patched, nil
+}
+
+// injectWebhooks sets the CA bundle of each webhook that references the Service, returning true if any were changed
+func injectWebhooks[T any](webhooks []T, clientConfigOf func(*T) *admissionregistrationv1.WebhookClientConfig, references func(*admissionregistrationv1.ServiceReference) bool, caBundle []byte) bool {
+	changed := false
+
+	for i := range webhooks {
+		clientConfig := clientConfigOf(&webhooks[i])
+
+		if !references(clientConfig.Service) || bytes.Equal(clientConfig.CABundle, caBundle) {
+			continue
+		}
+
+		clientConfig.CABundle = caBundle
+		changed = true
+	}
+
+	return changed
+}
+
+// needsRotation returns true if the serving certificate is due for renewal or was not issued for the Service
+func (b Bundle) needsRotation(cfg Config) bool {
+	cert, err := firstCertificate(b.Cert)
+
+	if err != nil {
+		return true
+	}
+
+	return !cfg.now().Before(b.RenewAt()) || !slices.Equal(cert.DNSNames, cfg.DNSNames())
+}
+
+func (b Bundle) data() map[string][]byte {
+	return map[string][]byte{
+		caCertKey: b.CABundle,
+		caKeyKey:  b.caKey,
+		CertFile:  b.Cert,
+		KeyFile:   b.Key,
+	}
+}
+
+// bundleOf returns the Bundle stored in the Secret, verifying that the serving certificate matches its key and was issued by the current CA
+func bundleOf(secret *corev1.Secret) (Bundle, error) {
+	b := Bundle{
+		CABundle: secret.Data[caCertKey],
+		Cert:     secret.Data[CertFile],
+		Key:      secret.Data[KeyFile],
+		caKey:    secret.Data[caKeyKey],
+	}
+
+	if _, err := tls.X509KeyPair(b.Cert, b.Key); err != nil {
+		return Bundle{}, fmt.Errorf("invalid serving certificate in secret %v/%v. %v", secret.Namespace, secret.Name, err)
+	}
+
+	cert, err := firstCertificate(b.Cert)
+
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	ca, err := firstCertificate(b.CABundle)
+
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return Bundle{}, fmt.Errorf("serving certificate in secret %v/%v was not issued by its ca. %v", secret.Namespace, secret.Name, err)
+	}
+
+	b.NotBefore, b.NotAfter = cert.NotBefore, cert.NotAfter
+
+	return b, nil
+}
+
+func firstCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no pem encoded certificate found")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate. %v", err)
+	}
+
+	return cert, nil
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number. %v", err)
+	}
+
+	return serial, nil
+}
@@ -0,0 +1,296 @@
+package certs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/comradequinn/kapi/internal/certs"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestConfig(now *time.Time) certs.Config {
+	return certs.Config{
+		ServiceName:      "test-webhooks",
+		ServiceNamespace: "test-namespace",
+		SecretName:       "test-webhooks-tls",
+		Validity:         90 * 24 * time.Hour,
+		Now:              func() time.Time { return *now },
+	}
+}
+
+func newTestClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("expected no error adding client-go types to scheme, got: %v", err)
+	}
+
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("expected no error adding apiextensions types to scheme, got: %v", err)
+	}
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestEnsure(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cfg := newTestConfig(&now)
+	c := newTestClient(t)
+
+	bundle, err := certs.Ensure(ctx, c, cfg)
+
+	if err != nil {
+		t.Fatalf("expected no error generating certificates, got: %v", err)
+	}
+
+	verify := func(t *testing.T, bundle certs.Bundle, at time.Time) {
+		t.Helper()
+
+		if _, err := tls.X509KeyPair(bundle.Cert, bundle.Key); err != nil {
+			t.Fatalf("expected serving certificate to match its key, got: %v", err)
+		}
+
+		roots := x509.NewCertPool()
+
+		if !roots.AppendCertsFromPEM(bundle.CABundle) {
+			t.Fatalf("expected ca bundle to contain pem encoded certificates")
+		}
+
+		block, _ := pem.Decode(bundle.Cert)
+		cert, err := x509.ParseCertificate(block.Bytes)
+
+		if err != nil {
+			t.Fatalf("expected serving certificate to be parsed, got: %v", err)
+		}
+
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: "test-webhooks.test-namespace.svc", Roots: roots, CurrentTime: at}); err != nil {
+			t.Fatalf("expected serving certificate to be verified by the ca bundle for the service, got: %v", err)
+		}
+	}
+
+	verify(t, bundle, now)
+
+	secret := &corev1.Secret{}
+
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cfg.ServiceNamespace, Name: cfg.SecretName}, secret); err != nil {
+		t.Fatalf("expected secret to be created, got: %v", err)
+	}
+
+	if secret.Type != corev1.SecretTypeTLS || !bytes.Equal(secret.Data[certs.CertFile], bundle.Cert) {
+		t.Fatalf("expected tls secret with serving certificate, got type %v", secret.Type)
+	}
+
+	t.Run("Reuse", func(t *testing.T) {
+		reused, err := certs.Ensure(ctx, c, cfg)
+
+		if err != nil {
+			t.Fatalf("expected no error loading certificates, got: %v", err)
+		}
+
+		if !bytes.Equal(reused.Cert, bundle.Cert) || !bytes.Equal(reused.CABundle, bundle.CABundle) {
+			t.Fatalf("expected certificates stored in secret to be reused before renewal")
+		}
+
+		if !reused.RenewAt().Equal(bundle.RenewAt()) {
+			t.Fatalf("expected renewal at %v, got: %v", bundle.RenewAt(), reused.RenewAt())
+		}
+	})
+
+	t.Run("Rotate", func(t *testing.T) {
+		rotateAt := bundle.RenewAt().Add(time.Minute)
+		rotatedCfg := newTestConfig(&rotateAt)
+
+		rotated, err := certs.Ensure(ctx, c, rotatedCfg)
+
+		if err != nil {
+			t.Fatalf("expected no error rotating certificates, got: %v", err)
+		}
+
+		if bytes.Equal(rotated.Cert, bundle.Cert) {
+			t.Fatalf("expected certificates to be rotated after renewal time")
+		}
+
+		if !bytes.HasSuffix(rotated.CABundle, bundle.CABundle) || bytes.Equal(rotated.CABundle, bundle.CABundle) {
+			t.Fatalf("expected ca bundle to contain the new ca followed by the previous ca")
+		}
+
+		verify(t, rotated, rotateAt)
+
+		if reloaded, err := certs.Ensure(ctx, c, rotatedCfg); err != nil || !bytes.Equal(reloaded.Cert, rotated.Cert) {
+			t.Fatalf("expected rotated certificates to be stored in secret, got error: %v", err)
+		}
+	})
+
+	t.Run("ServiceChanged", func(t *testing.T) {
+		changedCfg := newTestConfig(&now)
+		changedCfg.ServiceName = "other-webhooks"
+
+		changed, err := certs.Ensure(ctx, c, changedCfg)
+
+		if err != nil {
+			t.Fatalf("expected no error regenerating certificates, got: %v", err)
+		}
+
+		block, _ := pem.Decode(changed.Cert)
+		cert, _ := x509.ParseCertificate(block.Bytes)
+
+		if err := cert.VerifyHostname("other-webhooks.test-namespace.svc"); err != nil {
+			t.Fatalf("expected certificates to be regenerated for the changed service, got: %v", err)
+		}
+	})
+
+	t.Run("InvalidSecret", func(t *testing.T) {
+		invalid := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: cfg.ServiceNamespace, Name: "invalid-tls"},
+			Data:       map[string][]byte{certs.CertFile: []byte("invalid"), certs.KeyFile: []byte("invalid")},
+		}
+
+		invalidCfg := newTestConfig(&now)
+		invalidCfg.SecretName = invalid.Name
+
+		regenerated, err := certs.Ensure(ctx, newTestClient(t, invalid), invalidCfg)
+
+		if err != nil {
+			t.Fatalf("expected no error regenerating invalid certificates, got: %v", err)
+		}
+
+		verify(t, regenerated, now)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+
+	bundle, err := certs.Generate(newTestConfig(&now), nil)
+
+	if err != nil {
+		t.Fatalf("expected no error generating certificates, got: %v", err)
+	}
+
+	for i, expected := range []bool{true, false} {
+		changed, err := certs.Write(dir, bundle)
+
+		if err != nil {
+			t.Fatalf("expected no error writing certificates, got: %v", err)
+		}
+
+		if changed != expected {
+			t.Fatalf("expected changed to be %v on write %v, got: %v", expected, i, changed)
+		}
+	}
+
+	if _, err := tls.LoadX509KeyPair(filepath.Join(dir, certs.CertFile), filepath.Join(dir, certs.KeyFile)); err != nil {
+		t.Fatalf("expected written certificate and key to be loaded, got: %v", err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("expected only the certificate and key to be written, got: %v entries", len(entries))
+	}
+}
+
+func TestInjectCABundle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cfg := newTestConfig(&now)
+	caBundle := []byte("test-ca-bundle")
+
+	service := func(name string) *admissionregistrationv1.ServiceReference {
+		return &admissionregistrationv1.ServiceReference{Namespace: cfg.ServiceNamespace, Name: name}
+	}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-validating"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "matching.test.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: service(cfg.ServiceName)}},
+			{Name: "other.test.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: service("other")}},
+		},
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-mutating"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "matching.test.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: service(cfg.ServiceName)}},
+		},
+	}
+
+	unrelated := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-unrelated"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "url.test.io", ClientConfig: admissionregistrationv1.WebhookClientConfig{URL: ptrTo("https://example.com")}},
+		},
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "tests.test.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{
+						Service: &apiextensionsv1.ServiceReference{Namespace: cfg.ServiceNamespace, Name: cfg.ServiceName},
+					},
+				},
+			},
+		},
+	}
+
+	unconverted := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "unconverted.test.io"}}
+
+	c := newTestClient(t, validating, mutating, unrelated, crd, unconverted)
+
+	patched, err := certs.InjectCABundle(ctx, c, cfg, caBundle)
+
+	if err != nil {
+		t.Fatalf("expected no error injecting ca bundle, got: %v", err)
+	}
+
+	if len(patched) != 3 {
+		t.Fatalf("expected 3 patched resources, got: %v", patched)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(validating), validating); err != nil {
+		t.Fatalf("expected no error getting validating webhook configuration, got: %v", err)
+	}
+
+	if !bytes.Equal(validating.Webhooks[0].ClientConfig.CABundle, caBundle) || validating.Webhooks[1].ClientConfig.CABundle != nil {
+		t.Fatalf("expected ca bundle to be injected only into webhooks referencing the service")
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(mutating), mutating); err != nil || !bytes.Equal(mutating.Webhooks[0].ClientConfig.CABundle, caBundle) {
+		t.Fatalf("expected ca bundle to be injected into mutating webhook, got error: %v", err)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(unrelated), unrelated); err != nil || unrelated.Webhooks[0].ClientConfig.CABundle != nil {
+		t.Fatalf("expected ca bundle not to be injected into unrelated webhook, got error: %v", err)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(crd), crd); err != nil || !bytes.Equal(crd.Spec.Conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		t.Fatalf("expected ca bundle to be injected into conversion webhook, got error: %v", err)
+	}
+
+	if patched, err := certs.InjectCABundle(ctx, c, cfg, caBundle); err != nil || len(patched) != 0 {
+		t.Fatalf("expected no resources to be patched where the ca bundle is unchanged, got: %v, error: %v", patched, err)
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,260 @@
+// deepcopy provides a reflection based deep copy of arbitrary go values
+//
+// The copy func for each type is generated once, on first use, and cached. Types that implement `DeepCopyInto(*T)`, such as the
+// k8s api types, are copied using that method.
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+)
+
+type copyFunc func(dst, src reflect.Value)
+
+var (
+	copyFuncs sync.Map // map[reflect.Type]copyFunc
+)
+
+// Copy returns a deep copy of v.
+//
+// Exported fields are copied recursively, so that no pointer, slice, map or interface value is shared between v and its copy.
+// Unexported fields can not be set with reflection, so are copied as they are; any references they hold are shared with the copy.
+// Funcs and channels are likewise shared.
+//
+// Values that contain reference cycles are not supported.
+func Copy[T any](v T) T {
+	var out T
+
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.ValueOf(&out).Elem()
+
+	copyFuncFor(src.Type())(dst, src)
+
+	return out
+}
+
+// copyFuncFor returns the cached copyFunc for the specified type, generating it if required
+func copyFuncFor(t reflect.Type) copyFunc {
+	if f, ok := copyFuncs.Load(t); ok {
+		return f.(copyFunc)
+	}
+
+	// a recursive type references its own copyFunc while it is being generated, so an indirect func, which waits for
+	// generation to complete, is stored in the cache first
+	var (
+		wg sync.WaitGroup
+		f  copyFunc
+	)
+
+	wg.Add(1)
+
+	indirect, loaded := copyFuncs.LoadOrStore(t, copyFunc(func(dst, src reflect.Value) {
+		wg.Wait()
+		f(dst, src)
+	}))
+
+	if loaded {
+		return indirect.(copyFunc)
+	}
+
+	f = newCopyFunc(t)
+	wg.Done()
+	copyFuncs.Store(t, f)
+
+	return f
+}
+
+func newCopyFunc(t reflect.Type) copyFunc {
+	if method, ok := deepCopyInto(t); ok {
+		return func(dst, src reflect.Value) {
+			method.Call([]reflect.Value{addressable(src), dst.Addr()})
+		}
+	}
+
+	if !hasReferences(t, map[reflect.Type]bool{}) {
+		return func(dst, src reflect.Value) {
+			dst.Set(src)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return pointerCopyFunc(t)
+	case reflect.Slice:
+		return sliceCopyFunc(t)
+	case reflect.Array:
+		return arrayCopyFunc(t)
+	case reflect.Map:
+		return mapCopyFunc(t)
+	case reflect.Interface:
+		return interfaceCopyFunc
+	case reflect.Struct:
+		return structCopyFunc(t)
+	default:
+		return func(dst, src reflect.Value) {
+			dst.Set(src)
+		}
+	}
+}
+
+func pointerCopyFunc(t reflect.Type) copyFunc {
+	elemType := t.Elem()
+
+	return func(dst, src reflect.Value) {
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+
+		elem := reflect.New(elemType)
+		copyFuncFor(elemType)(elem.Elem(), src.Elem())
+		dst.Set(elem)
+	}
+}
+
+func sliceCopyFunc(t reflect.Type) copyFunc {
+	elemType := t.Elem()
+
+	return func(dst, src reflect.Value) {
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+
+		elemCopyFunc := copyFuncFor(elemType)
+		out := reflect.MakeSlice(t, src.Len(), src.Len())
+
+		for i := range src.Len() {
+			elemCopyFunc(out.Index(i), src.Index(i))
+		}
+
+		dst.Set(out)
+	}
+}
+
+func arrayCopyFunc(t reflect.Type) copyFunc {
+	elemType := t.Elem()
+
+	return func(dst, src reflect.Value) {
+		elemCopyFunc := copyFuncFor(elemType)
+
+		for i := range src.Len() {
+			elemCopyFunc(dst.Index(i), src.Index(i))
+		}
+	}
+}
+
+func mapCopyFunc(t reflect.Type) copyFunc {
+	keyType, elemType := t.Key(), t.Elem()
+
+	return func(dst, src reflect.Value) {
+		if src.IsNil() {
+			dst.SetZero()
+			return
+		}
+
+		keyCopyFunc, elemCopyFunc := copyFuncFor(keyType), copyFuncFor(elemType)
+		out := reflect.MakeMapWithSize(t, src.Len())
+		key, elem := reflect.New(keyType).Elem(), reflect.New(elemType).Elem()
+
+		for iter := src.MapRange(); iter.Next(); {
+			keyCopyFunc(key, iter.Key())
+			elemCopyFunc(elem, iter.Value())
+			out.SetMapIndex(key, elem)
+		}
+
+		dst.Set(out)
+	}
+}
+
+func interfaceCopyFunc(dst, src reflect.Value) {
+	if src.IsNil() {
+		dst.SetZero()
+		return
+	}
+
+	concrete := src.Elem()
+	out := reflect.New(concrete.Type()).Elem()
+	copyFuncFor(concrete.Type())(out, concrete)
+	dst.Set(out)
+}
+
+func structCopyFunc(t reflect.Type) copyFunc {
+	type fieldCopy struct {
+		index     int
+		fieldType reflect.Type
+	}
+
+	fieldCopies := []fieldCopy{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		if !field.IsExported() || !hasReferences(field.Type, map[reflect.Type]bool{}) {
+			continue
+		}
+
+		fieldCopies = append(fieldCopies, fieldCopy{index: i, fieldType: field.Type})
+	}
+
+	return func(dst, src reflect.Value) {
+		// copies all fields by value, including those that are unexported or hold no references, before the exported fields
+		// that hold references are replaced with deep copies
+		dst.Set(src)
+
+		for _, fc := range fieldCopies {
+			copyFuncFor(fc.fieldType)(dst.Field(fc.index), src.Field(fc.index))
+		}
+	}
+}
+
+// deepCopyInto returns the `DeepCopyInto(*T)` method of the pointer to t, where it is defined
+func deepCopyInto(t reflect.Type) (reflect.Value, bool) {
+	ptrType := reflect.PointerTo(t)
+	method, ok := ptrType.MethodByName("DeepCopyInto")
+
+	if !ok || method.Type.NumIn() != 2 || method.Type.In(1) != ptrType || method.Type.NumOut() != 0 {
+		return reflect.Value{}, false
+	}
+
+	return method.Func, true
+}
+
+// hasReferences returns true if values of type t can hold pointers, slices, maps, interfaces, funcs or channels,
+// and so cannot be copied by value alone
+func hasReferences(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return hasReferences(t.Elem(), visiting)
+	case reflect.Struct:
+		if visiting[t] {
+			return false
+		}
+
+		visiting[t] = true
+
+		for i := range t.NumField() {
+			if hasReferences(t.Field(i).Type, visiting) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// addressable returns a pointer to v, copying it to a new value where v itself is not addressable
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v.Addr()
+	}
+
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+
+	return ptr
+}
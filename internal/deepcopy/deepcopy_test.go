@@ -0,0 +1,212 @@
+package deepcopy_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/comradequinn/kapi"
+	"github.com/comradequinn/kapi/internal/deepcopy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type (
+	testSpec struct {
+		Name     string            `json:"name"`
+		Data     map[string]string `json:"data,omitempty"`
+		Values   []int64           `json:"values,omitempty"`
+		Ratio    float64           `json:"ratio"`
+		Raw      []byte            `json:"raw,omitempty"`
+		Nested   *testNested       `json:"nested,omitempty"`
+		Any      map[string]any    `json:"any,omitempty"`
+		Pod      corev1.PodSpec    `json:"pod"`
+		internal string
+	}
+	testNested struct {
+		Items []testItem `json:"items"`
+	}
+	testItem struct {
+		Key   string  `json:"key"`
+		Value *string `json:"value,omitempty"`
+	}
+	testStatus struct {
+		Copied bool `json:"copied"`
+	}
+	testResource     = kapi.CustomResource[testSpec, *testStatus, kapi.FieldUndefined]
+	testResourceList = kapi.CustomResourceList[*testResource]
+)
+
+// DeepCopyInto is defined to verify that it is used in place of the reflection based copy
+func (s *testStatus) DeepCopyInto(out *testStatus) {
+	out.Copied = true
+}
+
+func FuzzCopy(f *testing.F) {
+	f.Add("name", "key", "value", int64(1), 1.5, []byte("raw"), "internal")
+	f.Add("", "", "", int64(0), math.NaN(), []byte(nil), "")
+
+	f.Fuzz(func(t *testing.T, name, key, value string, i int64, ratio float64, raw []byte, internal string) {
+		original := newTestResource(name, key, value, i, ratio, raw, internal)
+		copied := original.DeepCopyObject().(*testResource)
+
+		if copied == original || copied.Spec.Nested == original.Spec.Nested || (len(original.Spec.Values) > 0 && &copied.Spec.Values[0] == &original.Spec.Values[0]) {
+			t.Fatalf("expected copy to share no references with the original")
+		}
+
+		if copied.Spec.internal != original.Spec.internal {
+			t.Fatalf("expected unexported field %q to be copied, got: %q", original.Spec.internal, copied.Spec.internal)
+		}
+
+		if !copied.Status.Copied {
+			t.Fatalf("expected status to be copied with its deepcopyinto method")
+		}
+
+		if math.IsNaN(ratio) {
+			if !math.IsNaN(copied.Spec.Ratio) {
+				t.Fatalf("expected NaN ratio to be copied, got: %v", copied.Spec.Ratio)
+			}
+			return
+		}
+
+		copied.Status.Copied = false
+
+		if !reflect.DeepEqual(original, copied) {
+			t.Fatalf("expected copy to equal the original.\noriginal: %+v\ncopy: %+v", original, copied)
+		}
+
+		if math.IsInf(ratio, 0) {
+			return
+		}
+
+		// the json implementation drops unexported fields, so they are excluded from the comparison with it
+		original.Spec.internal, copied.Spec.internal = "", ""
+
+		jsonCopied := jsonCopy(original)
+
+		if !reflect.DeepEqual(mustMarshal(t, jsonCopied), mustMarshal(t, copied)) {
+			t.Fatalf("expected copy to be equivalent to the json implementation.\njson: %s\ncopy: %s", mustMarshal(t, jsonCopied), mustMarshal(t, copied))
+		}
+
+		copied.Spec.Data[key] = "modified"
+		copied.Spec.Nested.Items[0].Key = "modified"
+		*copied.Spec.Nested.Items[0].Value = "modified"
+		copied.Spec.Any["nested"].(map[string]any)["value"] = "modified"
+
+		if original.Spec.Data[key] != value || original.Spec.Nested.Items[0].Key != key || *original.Spec.Nested.Items[0].Value != value || original.Spec.Any["nested"].(map[string]any)["value"] != value {
+			t.Fatalf("expected modifications to the copy not to affect the original, got: %+v", original.Spec)
+		}
+	})
+}
+
+func TestCopyList(t *testing.T) {
+	original := newTestResourceList(10)
+	copied := original.DeepCopyObject().(*testResourceList)
+
+	if len(copied.Items) != len(original.Items) || copied.Items[0] == original.Items[0] {
+		t.Fatalf("expected list items to be copied")
+	}
+
+	copied.Items[0].Spec.Name = "modified"
+
+	if original.Items[0].Spec.Name == "modified" {
+		t.Fatalf("expected modifications to the copied list not to affect the original")
+	}
+}
+
+func TestCopyNil(t *testing.T) {
+	var (
+		resource *testResource
+		list     *testResourceList
+	)
+
+	if resource.DeepCopyObject() != nil || list.DeepCopyObject() != nil {
+		t.Fatalf("expected nil copies of nil resources")
+	}
+
+	if copied := deepcopy.Copy[map[string]any](nil); copied != nil {
+		t.Fatalf("expected nil copy of nil map, got: %v", copied)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	for _, size := range []int{1, 100, 1000} {
+		list := newTestResourceList(size)
+
+		b.Run(fmt.Sprintf("reflect-%v", size), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for range b.N {
+				list.DeepCopyObject()
+			}
+		})
+
+		b.Run(fmt.Sprintf("json-%v", size), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for range b.N {
+				jsonCopy(list)
+			}
+		})
+	}
+}
+
+func newTestResource(name, key, value string, i int64, ratio float64, raw []byte, internal string) *testResource {
+	resource := &testResource{
+		Spec: testSpec{
+			Name:   name,
+			Data:   map[string]string{key: value},
+			Values: []int64{i, -i},
+			Ratio:  ratio,
+			Raw:    raw,
+			Nested: &testNested{
+				Items: []testItem{{Key: key, Value: &value}},
+			},
+			Any: map[string]any{
+				"nested": map[string]any{"value": value},
+				"list":   []any{value, float64(i)},
+			},
+			Pod: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: name, Args: []string{value}}},
+			},
+			internal: internal,
+		},
+		Status: &testStatus{},
+	}
+
+	resource.Name = name
+	resource.Labels = map[string]string{key: value}
+
+	return resource
+}
+
+func newTestResourceList(size int) *testResourceList {
+	list := &testResourceList{}
+
+	for i := range size {
+		list.Items = append(list.Items, newTestResource(fmt.Sprintf("resource-%v", i), "key", "value", int64(i), float64(i), []byte("raw"), "internal"))
+	}
+
+	return list
+}
+
+// jsonCopy is the previous, json based, implementation of DeepCopyObject
+func jsonCopy[T any](in *T) *T {
+	out := new(T)
+
+	b, _ := json.Marshal(in)
+	json.Unmarshal(b, out)
+
+	return out
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		t.Fatalf("unable to marshal %T. %v", v, err)
+	}
+
+	return string(b)
+}
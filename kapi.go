@@ -6,6 +6,8 @@ import (
 	"maps"
 	"reflect"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/comradequinn/kapi/internal/logconv"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -16,6 +18,8 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -26,12 +30,27 @@ type (
 	// - configure one or more ReconcilerFuncs that are executed when specified k8s cluster resource-change events occur
 	// - access a `client` that can be used to perform resource level CRUD operations against a k8s cluster
 	Cluster struct {
-		manager   manager.Manager
-		connected bool
+		manager        manager.Manager
+		uncachedClient client.WithWatch
+		controllers    map[schema.GroupVersionKind]controller.Controller
+		writes         *writeTracker
+		cacheScope     cacheScope
+		cacheScopes    map[reflect.Type]cacheScope
+		interceptors   []Interceptor
+		dryRun         bool
+		crds           []*apiextensionsv1.CustomResourceDefinition
+		conversions    *conversions
+		webhooks       WebhookConfig
+		certDir        string
+		caBundle       []byte
+		caBundleMu     sync.Mutex // serialises changes to the caBundle with the installation of the webhook configurations that include it
+		hooks          []hookRegistration
+		connected      bool
 	}
 	// ClusterConfig defines information about how to interact with a specific k8s cluster
 	ClusterConfig struct {
-		// TLS defines the directory in which the TLS certificates to use when serving any configured hooks are stored
+		// TLS defines the directory in which the TLS certificates to use when serving any configured hooks are stored.
+		// Where WebhookConfig.GenerateCerts is set, the generated certificates are written to this directory or, if it is unset, to a temporary directory
 		TLS string
 		// DisableCaching disables caching of cluster information locally.
 		//
@@ -46,6 +65,56 @@ type (
 		Namespaces []string
 		// CRDs defines any CRDs that the Cluster must recognise
 		CRDs []CRDs
+		// Cache defines how resources of specific types are cached, keyed by an instance of the resource type; for example `&corev1.Pod{}`.
+		//
+		// This can be used to limit the resources cached with label or field selectors, to strip data from resources before they are cached,
+		// or to cache a type in a different set of namespaces. Resource types without an entry are cached in the ClusterConfig.Namespaces
+		Cache map[KindType]CacheConfig
+		// Interceptors defines a chain of Interceptors that wrap the operations of all Clients created for the Cluster; for example to add
+		// standard labels, to restrict writes to specific namespaces or to record custom metrics.
+		//
+		// Interceptors are invoked in the order defined, after the built-in logging and metrics and before any Interceptors passed to the Client itself
+		Interceptors []Interceptor
+		// DryRunReconcilers causes every write made through a Client within a ReconcilerFunc to be executed as a dry-run, with the change
+		// it would have made logged. See DryRunReconciler to enable this for individual reconcilers
+		DryRunReconcilers bool
+		// InstallCRDs causes the CustomResourceDefinitions of the kinds defined in CRDs to be created, or updated, when the Cluster is connected.
+		// See ClusterConfig.CustomResourceDefinitions for details of how they are generated.
+		//
+		// The identity of the controller or operator must be permitted to create and update CustomResourceDefinitions
+		InstallCRDs bool
+		// Webhooks defines how the API server reaches the webhook server of the controller or operator, where it is deployed behind a Service
+		Webhooks WebhookConfig
+	}
+	// WebhookConfig defines the Service through which the API server reaches the webhook server of a controller or operator
+	WebhookConfig struct {
+		// ServiceName defines the name of the Service. Where set, kinds defined in more than one version are generated with a conversion webhook
+		ServiceName string
+		// ServiceNamespace defines the namespace of the Service
+		ServiceNamespace string
+		// ServicePort defines the port of the Service. By default, this is 443
+		ServicePort int32
+		// GenerateCerts causes a self-signed CA and serving certificate for the Service to be generated, as an alternative to provisioning them externally.
+		//
+		// The certificates are stored in a Secret in the ServiceNamespace, which is shared by all replicas, and rotated once two thirds of their validity
+		// has elapsed. The CA bundle of each webhook configuration and CustomResourceDefinition conversion webhook that references the Service is kept up to date.
+		//
+		// The identity of the controller or operator must be permitted to get, create and update Secrets in the ServiceNamespace, and to list and patch
+		// ValidatingWebhookConfigurations, MutatingWebhookConfigurations and CustomResourceDefinitions
+		GenerateCerts bool
+		// CertSecret defines the name of the Secret in which generated certificates are stored. By default, this is `<ServiceName>-webhook-tls`
+		CertSecret string
+		// CertValidity defines the validity of generated certificates. By default, this is one year
+		CertValidity time.Duration
+		// InstallConfigurations causes a ValidatingWebhookConfiguration and a MutatingWebhookConfiguration, named after the Service, to be created, or updated,
+		// when the Cluster is connected. They define a webhook for each Hook added to the Cluster, configured by its HookOptions; a configuration without
+		// webhooks is deleted.
+		//
+		// The configurations are installed once the webhook server is serving, so that writes are not rejected while it starts. Where leader election is
+		// enabled, they are installed by the leader.
+		//
+		// The identity of the controller or operator must be permitted to get, create, update and delete ValidatingWebhookConfigurations and MutatingWebhookConfigurations
+		InstallConfigurations bool
 	}
 	// LeaderElectionConfig defines the configuration for the leader elections of high availablity deployments
 	LeaderElectionConfig struct {
@@ -63,6 +132,17 @@ type (
 		APIGroup   string
 		APIVersion string
 		Kinds      map[string]KindType
+		// Types optionally defines kinds whose names are derived from their Go types, as an alternative to listing them in Kinds.
+		//
+		// The kind name of a type is the name of the type itself or, for an alias of CustomResource, the name of its Spec type without the
+		// `Spec` suffix; for example `Example` for `kapi.CustomResource[ExampleSpec, ...]`. The kind name of a list type is that of its items
+		// with a `List` suffix
+		Types []KindType
+		// Storage marks the APIVersion as the storage version of its kinds, where they are also defined in other versions of the same APIGroup.
+		// The storage version acts as the hub through which conversions between other versions are made; see AddConversion
+		Storage bool
+		// Options optionally defines how individual kinds, keyed by kind name, are represented in their generated CustomResourceDefinitions
+		Options map[string]KindOptions
 	}
 	// KindType is an interface that is implemented by any type that is based on kapi.CustomResource or kapi.CustomResourceLlist
 	KindType runtime.Object
@@ -111,6 +191,10 @@ func NewCluster(ctx context.Context, cfg ClusterConfig) (*Cluster, error) {
 		}
 	}
 
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid crds config for kapi.cluster. %w", err)
+	}
+
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
@@ -124,7 +208,7 @@ func NewCluster(ctx context.Context, cfg ClusterConfig) (*Cluster, error) {
 
 			metav1.AddToGroupVersion(scheme, gv)
 
-			for kindName, kindType := range maps.All(crd.Kinds) {
+			for kindName, kindType := range maps.All(crd.kinds()) {
 				gvk := gv.WithKind(kindName)
 				obs.LogFunc(ctx, 3, "registering kind type mapping in scheme", "gvk", gvk.String(), "kind_type", reflect.TypeOf(kindType).Elem().Name())
 				scheme.AddKnownTypeWithName(gvk, kindType)
@@ -142,16 +226,48 @@ func NewCluster(ctx context.Context, cfg ClusterConfig) (*Cluster, error) {
 		namespaces[ns] = cache.Config{}
 	}
 
+	cacheByObject, err := byObject(cfg.Cache, cfg.CRDs)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache config for kapi.cluster. %v", err)
+	}
+
+	if err := cfg.Webhooks.validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhooks config for kapi.cluster. %v", err)
+	}
+
+	certDir, err := cfg.certDir()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhooks config for kapi.cluster. %v", err)
+	}
+
+	var crds []*apiextensionsv1.CustomResourceDefinition
+
+	if cfg.InstallCRDs {
+		if crds, err = cfg.CustomResourceDefinitions(); err != nil {
+			return nil, fmt.Errorf("invalid crds config for kapi.cluster. %v", err)
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), manager.Options{
-		Scheme: scheme,
+		Scheme:         scheme,
+		MapperProvider: newCRDMapperProvider(cfg.CRDs),
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
 		Cache: cache.Options{
 			DefaultNamespaces: namespaces,
+			ByObject:          cacheByObject,
+		},
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// allows cached dynamic clients to be served from the cache, as typed clients are
+				Unstructured: true,
+			},
 		},
 		WebhookServer: webhook.NewServer(webhook.Options{
-			CertDir: cfg.TLS,
+			CertDir: certDir,
 		}),
 
 		LeaderElection:          cfg.LeaderElection.Enabled,
@@ -163,10 +279,35 @@ func NewCluster(ctx context.Context, cfg ClusterConfig) (*Cluster, error) {
 		return nil, fmt.Errorf("unable to create controller manager for kapi.cluster. %v", err)
 	}
 
+	// the uncached client is shared by all uncached kapi.clients and reuses the manager's rest mapper and http client,
+	// this avoids rebuilding both for each operation
+	uncachedClient, err := client.NewWithWatch(mgr.GetConfig(), client.Options{
+		Scheme:     mgr.GetScheme(),
+		Mapper:     mgr.GetRESTMapper(),
+		HTTPClient: mgr.GetHTTPClient(),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create uncached client for kapi.cluster. %v", err)
+	}
+
 	obs.LogFunc(ctx, 3, "created kapi.cluster", "namespaces", cfg.Namespaces)
 
+	defaultCacheScope, cacheScopes := newCacheScopes(namespaces, cacheByObject)
+
 	return &Cluster{
-		manager: mgr,
+		manager:        mgr,
+		uncachedClient: uncachedClient,
+		controllers:    map[schema.GroupVersionKind]controller.Controller{},
+		writes:         newWriteTracker(),
+		cacheScope:     defaultCacheScope,
+		cacheScopes:    cacheScopes,
+		interceptors:   cfg.Interceptors,
+		dryRun:         cfg.DryRunReconcilers,
+		crds:           crds,
+		conversions:    newConversions(scheme, cfg.CRDs),
+		webhooks:       cfg.Webhooks,
+		certDir:        certDir,
 	}, nil
 }
 
@@ -182,6 +323,26 @@ func (cluster *Cluster) Connect(ctx context.Context) error {
 
 	obs.LogFunc(ctx, 3, "connecting k8s.cluster")
 
+	if err := cluster.conversions.validate(); err != nil {
+		return fmt.Errorf("invalid conversions for kapi.cluster. %v", err)
+	}
+
+	if err := cluster.provisionCerts(ctx); err != nil {
+		return fmt.Errorf("unable to provision webhook certificates for kapi.cluster. %v", err)
+	}
+
+	if err := cluster.installCRDs(ctx); err != nil {
+		return fmt.Errorf("unable to install crds for kapi.cluster. %v", err)
+	}
+
+	if err := cluster.addWebhookInstaller(); err != nil {
+		return fmt.Errorf("unable to add webhook configuration installer for kapi.cluster. %v", err)
+	}
+
+	if err := cluster.injectCABundle(ctx); err != nil {
+		return fmt.Errorf("unable to inject ca bundle for kapi.cluster. %v", err)
+	}
+
 	if err := cluster.manager.Start(ctx); err != nil {
 		return fmt.Errorf("unable to start controller-runtime.manager for kapi.cluster. %v", err)
 	}
@@ -1,17 +1,51 @@
 package kapi
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/comradequinn/kapi/internal/certs"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 type (
@@ -20,14 +54,80 @@ type (
 	}
 	TestResource     = CustomResource[TestResourceSpec, FieldUndefined, FieldUndefined]
 	TestResourceList = CustomResourceList[*TestResource]
+
+	ScalableTestResourceSpec struct {
+		Replicas int32 `json:"replicas" kapi:"column=Desired"`
+	}
+	ScalableTestResourceStatus struct {
+		Replicas int32 `json:"replicas" kapi:"column=Current,wide"`
+	}
+	ScalableTestResource     = CustomResource[ScalableTestResourceSpec, ScalableTestResourceStatus, FieldUndefined]
+	ScalableTestResourceList = CustomResourceList[*ScalableTestResource]
+
+	ClusterTestResourceSpec struct {
+		Tier string `json:"tier"`
+	}
+	ClusterTestResource     = CustomResource[ClusterTestResourceSpec, FieldUndefined, FieldUndefined]
+	ClusterTestResourceList = CustomResourceList[*ClusterTestResource]
+
+	TaggedTestResourceSpec struct {
+		Replicas *int32             `json:"replicas,omitempty" kapi:"default=3,min=1,max=10"`
+		Tier     string             `json:"tier,omitempty" kapi:"required,enum=bronze|silver|gold"`
+		Name     string             `json:"name,omitempty" kapi:"pattern=^[a-z]+(-[a-z]+){0,3}$"`
+		Items    []TaggedTestItem   `json:"items,omitempty" kapi:"max=2"`
+		Options  *TaggedTestOptions `json:"options,omitempty" kapi:"default={}"`
+	}
+	TaggedTestItem struct {
+		Key string `json:"key" kapi:"min=1"`
+	}
+	TaggedTestOptions struct {
+		Enabled *bool `json:"enabled,omitempty" kapi:"default=true"`
+	}
+	TaggedTestResource = CustomResource[TaggedTestResourceSpec, FieldUndefined, FieldUndefined]
+
+	CELTestResourceSpec struct {
+		MinReplicas int32  `json:"minReplicas"`
+		MaxReplicas int32  `json:"maxReplicas"`
+		Region      string `json:"region,omitempty" kapi:"immutable"`
+		Prefix      string `json:"prefix,omitempty" cel:"self.startsWith('cel-')" celMessage:"must start with cel-"`
+	}
+	CELTestResource = CustomResource[CELTestResourceSpec, FieldUndefined, FieldUndefined]
+
+	VersionedTestResourceSpec struct {
+		Replicas int32 `json:"replicas"`
+	}
+	VersionedTestResource     = CustomResource[VersionedTestResourceSpec, FieldUndefined, FieldUndefined]
+	VersionedTestResourceList = CustomResourceList[*VersionedTestResource]
+
+	VersionedTestResourceV1Alpha1Spec struct {
+		Size string `json:"size"`
+	}
+	VersionedTestResourceV1Alpha1     = CustomResource[VersionedTestResourceV1Alpha1Spec, FieldUndefined, FieldUndefined]
+	VersionedTestResourceV1Alpha1List = CustomResourceList[*VersionedTestResourceV1Alpha1]
+
+	WidgetTestConfig struct {
+		Size string `json:"size"`
+	}
+
+	nonPointerTestKind struct{}
 )
 
+func (nonPointerTestKind) GetObjectKind() schema.ObjectKind { return schema.EmptyObjectKind }
+func (k nonPointerTestKind) DeepCopyObject() runtime.Object { return k }
+
+func (CELTestResourceSpec) CELRules() []CELRule {
+	return []CELRule{{Rule: "self.minReplicas <= self.maxReplicas", Message: "must not be less than minReplicas", FieldPath: ".maxReplicas"}}
+}
+
 var (
 	testCluster        = "kapi-test"
 	testNamespace      = "kapi-test"
 	cluster            *Cluster
+	clusterConfig      ClusterConfig
 	ctx                = context.Background()
 	reconcilerExecuted = make(chan struct{}, 1)
+
+	dynamicReconcilerExecuted = make(chan struct{}, 1)
 )
 
 func TestMain(m *testing.M) {
@@ -53,8 +153,7 @@ func TestMain(m *testing.M) {
 		},
 	})
 
-	var err error
-	cluster, err = NewCluster(ctx, ClusterConfig{
+	clusterConfig = ClusterConfig{
 		LeaderElection: LeaderElectionConfig{
 			Enabled:      true,
 			LockResource: "kapi-test-leader-election-lock",
@@ -66,13 +165,53 @@ func TestMain(m *testing.M) {
 			{
 				APIGroup:   "kapi-test.comradequinn.github.io",
 				APIVersion: "v1",
+				Storage:    true,
+				Kinds: map[string]KindType{
+					"TestResource":              &TestResource{},
+					"TestResourceList":          &TestResourceList{},
+					"ScalableTestResource":      &ScalableTestResource{},
+					"ScalableTestResourceList":  &ScalableTestResourceList{},
+					"ClusterTestResource":       &ClusterTestResource{},
+					"ClusterTestResourceList":   &ClusterTestResourceList{},
+					"VersionedTestResource":     &VersionedTestResource{},
+					"VersionedTestResourceList": &VersionedTestResourceList{},
+				},
+				Options: map[string]KindOptions{
+					"ScalableTestResource": {
+						Scale:      &ScaleOptions{},
+						ShortNames: []string{"str"},
+						Categories: []string{"kapi-test"},
+						PrinterColumns: []PrinterColumn{
+							{Name: "Selector", JSONPath: ".spec.selector", Priority: 1},
+						},
+					},
+					"ClusterTestResource": {
+						ClusterScoped: true,
+					},
+				},
+			},
+			{
+				APIGroup:   "kapi-test.comradequinn.github.io",
+				APIVersion: "v1alpha1",
 				Kinds: map[string]KindType{
-					"TestResource":     &TestResource{},
-					"TestResourceList": &TestResourceList{},
+					"VersionedTestResource":     &VersionedTestResourceV1Alpha1{},
+					"VersionedTestResourceList": &VersionedTestResourceV1Alpha1List{},
 				},
 			},
 		},
-	})
+		Cache: map[KindType]CacheConfig{
+			&corev1.ConfigMap{}: {
+				StripManagedFields: true,
+				StripAnnotations:   []string{"kapi-test-stripped"},
+			},
+			&corev1.ServiceAccount{}: {
+				LabelSelector: "kapi-test-cached=true",
+			},
+		},
+	}
+
+	var err error
+	cluster, err = NewCluster(ctx, clusterConfig)
 
 	if err != nil {
 		log.Fatalf("error creating kapi.cluster: %v", err)
@@ -93,6 +232,47 @@ func TestMain(m *testing.M) {
 		log.Fatalf("error creating kapi.cluster: %v", err)
 	}
 
+	err = AddConversion(ctx, cluster, func(from *VersionedTestResourceV1Alpha1) (*VersionedTestResource, error) {
+		replicas, err := strconv.Atoi(from.Spec.Size)
+		return &VersionedTestResource{Spec: VersionedTestResourceSpec{Replicas: int32(replicas)}}, err
+	})
+
+	if err != nil {
+		log.Fatalf("error creating kapi.conversion: %v", err)
+	}
+
+	err = AddConversion(ctx, cluster, func(from *VersionedTestResource) (*VersionedTestResourceV1Alpha1, error) {
+		return &VersionedTestResourceV1Alpha1{Spec: VersionedTestResourceV1Alpha1Spec{Size: strconv.Itoa(int(from.Spec.Replicas))}}, nil
+	})
+
+	if err != nil {
+		log.Fatalf("error creating kapi.conversion: %v", err)
+	}
+
+	err = AddIndex(ctx, cluster, "kapi-test-index", func(c *corev1.ConfigMap) []string {
+		return []string{c.Labels["kapi-test-index"]}
+	})
+
+	if err != nil {
+		log.Fatalf("error creating kapi.index: %v", err)
+	}
+
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+	err = AddDynamicReconciler(ctx, cluster, secretGVK, nil, func(ctx context.Context, evt ReconcileEventType, resource *unstructured.Unstructured) error {
+		if evt == ReconcileEventTypeCreatedOrUpdated && resource.GetName() == "dynamic-test-data" && resource.GroupVersionKind() == secretGVK {
+			select {
+			case dynamicReconcilerExecuted <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Fatalf("error creating kapi.cluster: %v", err)
+	}
+
 	go func() {
 		if err := cluster.Connect(ctx); err != nil {
 			log.Fatalf("error connecting to cluster: %v", err)
@@ -159,6 +339,13 @@ func TestKapiClient(t *testing.T) {
 		t.Fatalf("expected no error deleting configmap, got: %v", err)
 	}
 
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	if err = klient.WaitForDeletion(waitCtx, cfgMap.Namespace, cfgMap.Name); err != nil {
+		t.Fatalf("expected no error waiting for configmap deletion, got: %v", err)
+	}
+
 	configMaps, err = klient.List(ctx)
 
 	if err != nil {
@@ -170,116 +357,1603 @@ func TestKapiClient(t *testing.T) {
 	}
 }
 
-func TestReconciler(t *testing.T) {
-	// the testmain func configures a reconciler that should be triggered by the client tests; when it is, it closes the reconcilerExecuted channel
-	select {
-	case <-reconcilerExecuted:
-	case <-time.After(time.Second * 30):
-		t.Fatalf("reconciler did not execute")
+func TestKapiClientDeleteAllOf(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false)
+
+	for _, name := range []string{"delete-all-of-1", "delete-all-of-2"} {
+		cfgMap := &corev1.ConfigMap{}
+		cfgMap.Name = name
+		cfgMap.Namespace = testNamespace
+		cfgMap.Labels = map[string]string{"kapi-test": "delete-all-of"}
+
+		if err := klient.Create(ctx, cfgMap); err != nil {
+			t.Fatalf("expected no error creating configmap %v, got: %v", name, err)
+		}
+	}
+
+	// the api server cannot delete a collection of namespaced resources across namespaces, so an empty namespace must be rejected
+	if err := klient.DeleteAllOf(ctx, "", "kapi-test=delete-all-of"); err == nil || !strings.Contains(err.Error(), "a namespace is required") {
+		t.Fatalf("expected namespace required error deleting all configmaps without a namespace, got: %v", err)
+	}
+
+	if _, err := klient.Get(ctx, testNamespace, "delete-all-of-1"); err != nil {
+		t.Fatalf("expected configmap to remain after rejected delete, got: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	if err := klient.DeleteAllOf(waitCtx, testNamespace, "kapi-test=delete-all-of", WithPropagationPolicy(PropagationForeground), WithWait()); err != nil {
+		t.Fatalf("expected no error deleting all configmaps, got: %v", err)
+	}
+
+	configMaps, err := klient.List(ctx)
+
+	if err != nil {
+		t.Fatalf("expected no error listing configmaps, got: %v", err)
+	}
+
+	for _, cfgMap := range configMaps.Items {
+		if cfgMap.Labels["kapi-test"] == "delete-all-of" {
+			t.Fatalf("expected configmap %v to have been deleted", cfgMap.Name)
+		}
 	}
 }
 
-func TestCRD(t *testing.T) {
-	crd := apiextensionsv1.CustomResourceDefinition{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "testresources.kapi-test.comradequinn.github.io",
-		},
-		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
-			Group: "kapi-test.comradequinn.github.io",
-			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
-				{
-					Name:    "v1",
-					Served:  true,
-					Storage: true,
-					Schema: &apiextensionsv1.CustomResourceValidation{
-						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
-							Type: "object",
-							Properties: map[string]apiextensionsv1.JSONSchemaProps{
-								"spec": {
-									Type: "object",
-									Properties: map[string]apiextensionsv1.JSONSchemaProps{
-										"testData": {Type: "string"},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-			Scope: apiextensionsv1.NamespaceScoped,
-			Names: apiextensionsv1.CustomResourceDefinitionNames{
-				Plural:   "testresources",
-				Singular: "testresource",
-				Kind:     "TestResource",
-			},
+func TestKapiClientErrors(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false)
+
+	_, err := klient.Get(ctx, testNamespace, "does-not-exist")
+
+	if !IsNotFound(fmt.Errorf("wrapped error. %w", err)) {
+		t.Fatalf("expected not found error getting missing configmap, got: %v", err)
+	}
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "errors-data"
+	cfgMap.Namespace = testNamespace
+
+	if err = klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, cfgMap)
+
+	cfgMap.ResourceVersion = ""
+
+	if err = klient.Create(ctx, cfgMap); !IsAlreadyExists(err) {
+		t.Fatalf("expected already exists error re-creating configmap, got: %v", err)
+	}
+
+	invalidCfgMap := &corev1.ConfigMap{}
+	invalidCfgMap.Name = "Invalid_Name"
+	invalidCfgMap.Namespace = testNamespace
+
+	err = klient.Create(ctx, invalidCfgMap)
+
+	if !IsInvalid(err) {
+		t.Fatalf("expected invalid error creating configmap with invalid name, got: %v", err)
+	}
+
+	invalidErr, ok := AsInvalidError(err)
+
+	if !ok || len(invalidErr.Causes) == 0 || invalidErr.Causes[0].Field != "metadata.name" {
+		t.Fatalf("expected invalid error with a metadata.name cause, got: %+v", invalidErr)
+	}
+}
+
+func TestKapiClientInterceptors(t *testing.T) {
+	actions := []Action{}
+
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false, WithInterceptors(
+		NamespaceInterceptor(testNamespace),
+		LabelInterceptor(map[string]string{"kapi-test": "interceptors"}),
+		func(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+			actions = append(actions, op.Action)
+			return next(ctx)
 		},
+	))
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "interceptors-data"
+	cfgMap.Namespace = "default"
+
+	if err := klient.Create(ctx, cfgMap); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error creating configmap outside of permitted namespaces, got: %v", err)
 	}
 
-	crdKlient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, true)
+	cfgMap.Namespace = testNamespace
 
-	if err := crdKlient.Create(ctx, &crd); err != nil {
-		t.Fatalf("expected no error creating custom resource definition, got: %v", err)
+	if err := klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, cfgMap)
+
+	if err := klient.Patch(ctx, cfgMap, PatchTypeMerge, []byte(`{"data":{"patched":"true"}}`)); err != nil {
+		t.Fatalf("expected no error patching configmap, got: %v", err)
 	}
 
-	{ // poll for the CRD to be established for up to 30 seconds (expected time should be <1s)
-		crdReady := false
+	actual, err := klient.Get(ctx, testNamespace, cfgMap.Name)
 
-		for range 6 {
-			crds, err := crdKlient.List(ctx)
+	if err != nil {
+		t.Fatalf("expected no error getting configmap, got: %v", err)
+	}
 
-			if err != nil {
-				t.Fatalf("expected no error listing custom resource definitions, got: %v", err)
-			}
+	if actual.Labels["kapi-test"] != "interceptors" || actual.Data["patched"] != "true" {
+		t.Fatalf("expected configmap to be labelled and patched, got: %+v", actual)
+	}
 
-			if len(crds.Items) == 1 {
-				crdReady = true
-				break
-			}
-			<-time.After(time.Second * 5)
-		}
+	if expected := []Action{ActionCreate, ActionPatch, ActionGet}; !slices.Equal(actions, expected) {
+		t.Fatalf("expected intercepted actions to be %v, got: %v", expected, actions)
+	}
+}
+
+func TestKapiClientDryRun(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false)
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "dry-run-data"
+	cfgMap.Namespace = testNamespace
+	cfgMap.Data = map[string]string{"state": "original"}
+
+	if err := klient.Create(ctx, cfgMap, DryRun()); err != nil {
+		t.Fatalf("expected no error dry-run creating configmap, got: %v", err)
+	}
+
+	if _, err := klient.Get(ctx, testNamespace, cfgMap.Name); !IsNotFound(err) {
+		t.Fatalf("expected not found error getting dry-run created configmap, got: %v", err)
+	}
+
+	cfgMap.ResourceVersion = ""
+
+	if err := klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, cfgMap)
 
-		if !crdReady {
-			t.Fatalf("expected custom resource definition to be ready")
+	reconcilerFunc := DryRunReconciler(func(ctx context.Context, evt ReconcileEventType, resource *corev1.ConfigMap) error {
+		resource.Data["state"] = "modified"
+
+		if err := klient.Update(ctx, resource); err != nil {
+			return err
 		}
+
+		return klient.Delete(ctx, resource)
+	})
+
+	if err := reconcilerFunc(ctx, ReconcileEventTypeCreatedOrUpdated, cfgMap.DeepCopy()); err != nil {
+		t.Fatalf("expected no error executing dry-run reconciler, got: %v", err)
 	}
 
-	klient := ClientFor[*TestResource, *TestResourceList](ctx, cluster, false)
+	actual, err := klient.Get(ctx, testNamespace, cfgMap.Name)
 
-	expectedTestResource := TestResource{
-		Spec: TestResourceSpec{
-			TestData: "test data",
-		},
+	if err != nil {
+		t.Fatalf("expected no error getting configmap after dry-run reconciler, got: %v", err)
 	}
 
-	expectedTestResource.Name = "test-resource"
-	expectedTestResource.Namespace = testNamespace
+	if actual.Data["state"] != "original" {
+		t.Fatalf("expected configmap to be unmodified by dry-run reconciler, got: %+v", actual.Data)
+	}
 
-	if err := klient.Create(ctx, &expectedTestResource); err != nil {
-		t.Fatalf("expected no error creating custom resource, got: %v", err)
+	modified := actual.DeepCopy()
+	modified.Data["state"] = "modified"
+
+	if diff, err := dryRunDiff(actual, modified); err != nil || diff != `{"data":{"state":"modified"}}` {
+		t.Fatalf("expected dry-run diff of modified data, got: %v, %v", diff, err)
 	}
+}
 
-	testResource, err := klient.Get(ctx, expectedTestResource.Namespace, expectedTestResource.Name)
+func TestKapiClientImpersonation(t *testing.T) {
+	var user string
+
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, true, WithInterceptors(func(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+		user = op.User
+		return next(ctx)
+	}))
+
+	unprivileged, err := klient.As("kapi-test-user")
 
 	if err != nil {
-		t.Fatalf("expected no error getting custom resource, got: %v", err)
+		t.Fatalf("expected no error creating impersonated client, got: %v", err)
 	}
 
-	if testResource.Spec.TestData != expectedTestResource.Spec.TestData {
-		t.Fatalf("expected test data %v, got: %v", expectedTestResource.Spec.TestData, testResource.Spec.TestData)
+	if _, err := unprivileged.List(ctx); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error listing configmaps as unprivileged user, got: %v", err)
 	}
 
-	testResources, err := klient.List(ctx)
+	if user != "kapi-test-user" {
+		t.Fatalf("expected intercepted operation user to be kapi-test-user, got: %q", user)
+	}
+
+	privileged, err := klient.As("kapi-test-admin", "system:masters")
 
 	if err != nil {
-		t.Fatalf("expected no error getting custom resource list, got: %v", err)
+		t.Fatalf("expected no error creating impersonated client, got: %v", err)
 	}
 
-	if len(testResources.Items) != 1 {
-		t.Fatalf("expected 1 custom resource in list items, got: %v", len(testResources.Items))
+	if _, err := privileged.List(ctx); err != nil {
+		t.Fatalf("expected no error listing configmaps as privileged user, got: %v", err)
+	}
+}
+
+func TestDynamicClient(t *testing.T) {
+	klient := DynamicClientFor(ctx, cluster, schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, false)
+
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetName("dynamic-test-data")
+	secret.SetNamespace(testNamespace)
+	unstructured.SetNestedStringMap(secret.Object, map[string]string{"key1": "value1"}, "stringData")
+
+	if err := klient.Create(ctx, secret); err != nil {
+		t.Fatalf("expected no error creating secret, got: %v", err)
 	}
 
-	if testResources.Items[0].Spec.TestData != expectedTestResource.Spec.TestData {
-		t.Fatalf("expected list[0] test data %v, got: %v", expectedTestResource.Spec.TestData, testResource.Spec.TestData)
+	secret, err := klient.Get(ctx, testNamespace, "dynamic-test-data")
+
+	if err != nil {
+		t.Fatalf("expected no error getting secret, got: %v", err)
+	}
+
+	if value, _, _ := unstructured.NestedString(secret.Object, "data", "key1"); value == "" {
+		t.Fatalf("expected data for key1 in secret, got: %+v", secret.Object)
+	}
+
+	secrets, err := klient.List(ctx)
+
+	if err != nil {
+		t.Fatalf("expected no error listing secrets, got: %v", err)
+	}
+
+	if len(secrets.Items) == 0 {
+		t.Fatalf("expected at least 1 secret in list items, got: 0")
+	}
+
+	select {
+	case <-dynamicReconcilerExecuted:
+	case <-time.After(time.Second * 30):
+		t.Fatalf("dynamic reconciler did not execute")
+	}
+
+	if err = klient.Delete(ctx, secret, WithWait()); err != nil {
+		t.Fatalf("expected no error deleting secret, got: %v", err)
+	}
+}
+
+func TestMetadataClient(t *testing.T) {
+	klient := MetadataClientFor(ctx, cluster, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, true)
+
+	watchCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	events, err := klient.Watch(watchCtx, testNamespace)
+
+	if err != nil {
+		t.Fatalf("expected no error watching configmap metadata, got: %v", err)
+	}
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "metadata-test-data"
+	cfgMap.Namespace = testNamespace
+	cfgMap.Labels = map[string]string{"kapi-test": "metadata"}
+	cfgMap.Data = map[string]string{"key1": "value1"}
+
+	typedKlient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false)
+
+	if err := typedKlient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer typedKlient.Delete(ctx, cfgMap)
+
+	for evt := range events {
+		if evt.Type == WatchEventTypeAdded && evt.Resource.Name == cfgMap.Name {
+			break
+		}
+	}
+
+	if watchCtx.Err() != nil {
+		t.Fatalf("expected watch event for configmap creation")
+	}
+
+	resource, err := klient.WaitFor(watchCtx, testNamespace, cfgMap.Name, func(r *metav1.PartialObjectMetadata) bool { return true })
+
+	if err != nil {
+		t.Fatalf("expected no error waiting for configmap metadata, got: %v", err)
+	}
+
+	if resource.Labels["kapi-test"] != "metadata" {
+		t.Fatalf("expected label kapi-test=metadata on configmap metadata, got: %+v", resource.Labels)
+	}
+}
+
+func TestKapiClientListByIndex(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, true)
+
+	for _, name := range []string{"index-1", "index-2", "index-3"} {
+		cfgMap := &corev1.ConfigMap{}
+		cfgMap.Name = name
+		cfgMap.Namespace = testNamespace
+		cfgMap.Labels = map[string]string{"kapi-test-index": "odd"}
+
+		if name == "index-2" {
+			cfgMap.Labels["kapi-test-index"] = "even"
+		}
+
+		if err := klient.Create(ctx, cfgMap); err != nil {
+			t.Fatalf("expected no error creating configmap %v, got: %v", name, err)
+		}
+
+		defer klient.Delete(ctx, cfgMap)
+	}
+
+	for range 10 { // allow time for the cache to observe the created configmaps
+		configMaps, err := klient.ListByIndex(ctx, "kapi-test-index", "odd")
+
+		if err != nil {
+			t.Fatalf("expected no error listing configmaps by index, got: %v", err)
+		}
+
+		if len(configMaps.Items) == 2 {
+			return
+		}
+
+		<-time.After(time.Second)
+	}
+
+	t.Fatalf("expected 2 configmaps indexed under odd")
+}
+
+func TestCacheConfig(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, true)
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "cache-config-data"
+	cfgMap.Namespace = testNamespace
+	cfgMap.Annotations = map[string]string{"kapi-test-stripped": "true", "kapi-test-retained": "true"}
+
+	if err := klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, cfgMap)
+
+	for range 10 { // allow time for the cache to observe the created configmap
+		cached, err := klient.Get(ctx, cfgMap.Namespace, cfgMap.Name)
+
+		if IsNotFound(err) {
+			<-time.After(time.Second)
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("expected no error getting configmap, got: %v", err)
+		}
+
+		if len(cached.ManagedFields) > 0 || cached.Annotations["kapi-test-stripped"] != "" || cached.Annotations["kapi-test-retained"] == "" {
+			t.Fatalf("expected managed fields and stripped annotation to be removed from cached configmap, got: %+v", cached.ObjectMeta)
+		}
+
+		return
+	}
+
+	t.Fatalf("expected configmap to be cached")
+}
+
+func TestKapiClientReadYourWrites(t *testing.T) {
+	klient := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, true, WithReadYourWrites(time.Second*10))
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "read-your-writes-data"
+	cfgMap.Namespace = testNamespace
+	cfgMap.Data = map[string]string{"key1": "value1"}
+
+	if err := klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	cfgMap, err := klient.Get(ctx, cfgMap.Namespace, cfgMap.Name)
+
+	if err != nil {
+		t.Fatalf("expected no error getting configmap immediately after creation, got: %v", err)
+	}
+
+	cfgMap.Data["key1"] = "value2"
+
+	if err = klient.Update(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error updating configmap, got: %v", err)
+	}
+
+	configMaps, err := klient.List(ctx)
+
+	if err != nil {
+		t.Fatalf("expected no error listing configmaps, got: %v", err)
+	}
+
+	for _, listed := range configMaps.Items {
+		if listed.Name == cfgMap.Name && listed.Data["key1"] != "value2" {
+			t.Fatalf("expected listed configmap to reflect update, got: %v", listed.Data["key1"])
+		}
+	}
+
+	if err = klient.Delete(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error deleting configmap, got: %v", err)
+	}
+
+	if _, err = klient.Get(ctx, cfgMap.Namespace, cfgMap.Name); !IsNotFound(err) {
+		t.Fatalf("expected not found error getting configmap immediately after deletion, got: %v", err)
+	}
+}
+
+func TestKapiClientReadYourWritesUncacheable(t *testing.T) {
+	klient := ClientFor[*corev1.ServiceAccount, *corev1.ServiceAccountList](ctx, cluster, true, WithReadYourWrites(time.Second*10))
+
+	serviceAccount := &corev1.ServiceAccount{}
+	serviceAccount.Name = "read-your-writes-uncacheable"
+	serviceAccount.Namespace = testNamespace
+
+	// the service account lacks the label required by the cache config, so the cache never holds it and reads must not wait for it to
+	if err := klient.Create(ctx, serviceAccount); err != nil {
+		t.Fatalf("expected no error creating service account, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, serviceAccount)
+
+	start := time.Now()
+
+	if _, err := klient.Get(ctx, serviceAccount.Namespace, serviceAccount.Name); !IsNotFound(err) {
+		t.Fatalf("expected not found error getting service account excluded from the cache, got: %v", err)
+	}
+
+	if _, err := klient.List(ctx); err != nil {
+		t.Fatalf("expected no error listing service accounts, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected reads of a write excluded from the cache not to block, took: %v", elapsed)
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	defaultScope, scopes := newCacheScopes(map[string]cache.Config{"kapi-test": {}}, map[client.Object]cache.ByObject{
+		&corev1.ServiceAccount{}: {
+			Label: labels.SelectorFromSet(labels.Set{"kapi-test-cached": "true"}),
+			Field: fields.OneTermEqualSelector("metadata.name", "cached"),
+		},
+	})
+
+	serviceAccount := func(namespace, name string, labels map[string]string) *corev1.ServiceAccount {
+		return &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+	}
+
+	scope := scopes[reflect.TypeOf(&corev1.ServiceAccount{})]
+
+	for _, tc := range []struct {
+		name     string
+		scope    cacheScope
+		resource client.Object
+		expected bool
+	}{
+		{"in namespace", defaultScope, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kapi-test"}}, true},
+		{"outside namespace", defaultScope, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "other"}}, false},
+		{"cluster scoped", defaultScope, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kapi-test"}}, true},
+		{"matching selectors", scope, serviceAccount("kapi-test", "cached", map[string]string{"kapi-test-cached": "true"}), true},
+		{"label excluded", scope, serviceAccount("kapi-test", "cached", nil), false},
+		{"field excluded", scope, serviceAccount("kapi-test", "uncached", map[string]string{"kapi-test-cached": "true"}), false},
+	} {
+		if actual := tc.scope.holds(tc.resource); actual != tc.expected {
+			t.Errorf("%v: expected holds to be %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	// the testmain func configures a reconciler that should be triggered by the client tests; when it is, it closes the reconcilerExecuted channel
+	select {
+	case <-reconcilerExecuted:
+	case <-time.After(time.Second * 30):
+		t.Fatalf("reconciler did not execute")
+	}
+}
+
+func TestReconcilerDuplicateKind(t *testing.T) {
+	// the manager is not started, so the cluster it is configured for need not exist. controller names are validated as unique across the
+	// process, so validation is skipped to allow a reconciler for configmaps to be added alongside that of the testmain func
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "https://127.0.0.1:1"}, manager.Options{
+		Scheme:     clientgoscheme.Scheme,
+		Controller: config.Controller{SkipNameValidation: ptrTo(true)},
+	})
+
+	if err != nil {
+		t.Fatalf("expected no error creating manager, got: %v", err)
+	}
+
+	unconnectedCluster := &Cluster{manager: mgr, controllers: map[schema.GroupVersionKind]controller.Controller{}}
+	reconcilerFunc := func(context.Context, ReconcileEventType, *corev1.ConfigMap) error { return nil }
+
+	if err := AddReconciler(ctx, unconnectedCluster, nil, reconcilerFunc); err != nil {
+		t.Fatalf("expected no error adding reconciler, got: %v", err)
+	}
+
+	if err := AddReconciler(ctx, unconnectedCluster, nil, reconcilerFunc); err == nil || !strings.Contains(err.Error(), "already been added") {
+		t.Fatalf("expected error adding a second reconciler for the same kind, got: %v", err)
+	}
+}
+
+func TestPreserveCABundle(t *testing.T) {
+	crdWithCABundle := func(caBundle []byte) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{CABundle: caBundle},
+				},
+			},
+		}}
+	}
+
+	crd := crdWithCABundle(nil)
+	preserveCABundle(crd, crdWithCABundle([]byte("injected")))
+
+	if actual := string(crd.Spec.Conversion.Webhook.ClientConfig.CABundle); actual != "injected" {
+		t.Fatalf("expected injected ca bundle to be preserved, got: %q", actual)
+	}
+
+	crd = crdWithCABundle([]byte("generated"))
+	preserveCABundle(crd, crdWithCABundle([]byte("injected")))
+
+	if actual := string(crd.Spec.Conversion.Webhook.ClientConfig.CABundle); actual != "generated" {
+		t.Fatalf("expected generated ca bundle to be retained, got: %q", actual)
+	}
+
+	crd = crdWithCABundle(nil)
+	preserveCABundle(crd, &apiextensionsv1.CustomResourceDefinition{})
+
+	if actual := crd.Spec.Conversion.Webhook.ClientConfig.CABundle; actual != nil {
+		t.Fatalf("expected no ca bundle where none exists, got: %q", actual)
+	}
+}
+
+func TestCRD(t *testing.T) {
+	crd := apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testresources.kapi-test.comradequinn.github.io",
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "kapi-test.comradequinn.github.io",
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    "v1",
+					Served:  true,
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"testData": {Type: "string"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   "testresources",
+				Singular: "testresource",
+				Kind:     "TestResource",
+			},
+		},
+	}
+
+	crdKlient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, true)
+
+	if err := crdKlient.Create(ctx, &crd); err != nil {
+		t.Fatalf("expected no error creating custom resource definition, got: %v", err)
+	}
+
+	{ // wait for the CRD to be established for up to 30 seconds (expected time should be <1s)
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		if _, err := crdKlient.WaitFor(waitCtx, "", crd.Name, CRDEstablished); err != nil {
+			t.Fatalf("expected custom resource definition to be established, got: %v", err)
+		}
+	}
+
+	klient := ClientFor[*TestResource, *TestResourceList](ctx, cluster, false)
+
+	expectedTestResource := TestResource{
+		Spec: TestResourceSpec{
+			TestData: "test data",
+		},
+	}
+
+	expectedTestResource.Name = "test-resource"
+	expectedTestResource.Namespace = testNamespace
+
+	if err := klient.Create(ctx, &expectedTestResource); err != nil {
+		t.Fatalf("expected no error creating custom resource, got: %v", err)
+	}
+
+	testResource, err := klient.Get(ctx, expectedTestResource.Namespace, expectedTestResource.Name)
+
+	if err != nil {
+		t.Fatalf("expected no error getting custom resource, got: %v", err)
+	}
+
+	if testResource.Spec.TestData != expectedTestResource.Spec.TestData {
+		t.Fatalf("expected test data %v, got: %v", expectedTestResource.Spec.TestData, testResource.Spec.TestData)
+	}
+
+	testResources, err := klient.List(ctx)
+
+	if err != nil {
+		t.Fatalf("expected no error getting custom resource list, got: %v", err)
+	}
+
+	if len(testResources.Items) != 1 {
+		t.Fatalf("expected 1 custom resource in list items, got: %v", len(testResources.Items))
+	}
+
+	if testResources.Items[0].Spec.TestData != expectedTestResource.Spec.TestData {
+		t.Fatalf("expected list[0] test data %v, got: %v", expectedTestResource.Spec.TestData, testResource.Spec.TestData)
+	}
+}
+
+func TestScale(t *testing.T) {
+	crds, err := clusterConfig.CustomResourceDefinitions()
+
+	if err != nil {
+		t.Fatalf("expected no error generating custom resource definitions, got: %v", err)
+	}
+
+	i := slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "ScalableTestResource"
+	})
+
+	if i == -1 {
+		t.Fatalf("expected custom resource definition for ScalableTestResource, got: %+v", crds)
+	}
+
+	crd := crds[i]
+
+	if crd.Name != "scalabletestresources.kapi-test.comradequinn.github.io" || crd.Spec.Versions[0].Subresources == nil || crd.Spec.Versions[0].Subresources.Scale == nil || crd.Spec.Versions[0].Subresources.Status == nil {
+		t.Fatalf("expected custom resource definition with status and scale subresources, got: %+v", crd)
+	}
+
+	crdKlient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, true)
+
+	if err := crdKlient.Create(ctx, crd); err != nil {
+		t.Fatalf("expected no error creating custom resource definition, got: %v", err)
+	}
+
+	{ // wait for the CRD to be established for up to 30 seconds (expected time should be <1s)
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		if _, err := crdKlient.WaitFor(waitCtx, "", crd.Name, CRDEstablished); err != nil {
+			t.Fatalf("expected custom resource definition to be established, got: %v", err)
+		}
+	}
+
+	klient := For[*ScalableTestResource](ctx, cluster, false)
+
+	resource := &ScalableTestResource{Spec: ScalableTestResourceSpec{Replicas: 1}}
+	resource.Name = "scalable-test-resource"
+	resource.Namespace = testNamespace
+
+	if err := klient.Create(ctx, resource); err != nil {
+		t.Fatalf("expected no error creating scalable test resource, got: %v", err)
+	}
+
+	scale, err := klient.GetScale(ctx, testNamespace, resource.Name)
+
+	if err != nil || scale.Spec.Replicas != 1 {
+		t.Fatalf("expected scale with 1 replica, got: %+v, %v", scale, err)
+	}
+
+	scale.Spec.Replicas = 3
+
+	if err := klient.UpdateScale(ctx, scale); err != nil {
+		t.Fatalf("expected no error updating scale, got: %v", err)
+	}
+
+	if resource, err = klient.Get(ctx, testNamespace, resource.Name); err != nil || resource.Spec.Replicas != 3 {
+		t.Fatalf("expected scalable test resource with 3 replicas, got: %+v, %v", resource, err)
+	}
+
+	if err := klient.Update(ctx, resource, SubresourceScale); err == nil {
+		t.Fatalf("expected error updating scale subresource with update")
+	}
+}
+
+func TestScaleDryRunInterceptors(t *testing.T) {
+	deployment := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: ptrTo(int32(1))}}
+	deployment.Name = "scale-dry-run"
+	deployment.Namespace = testNamespace
+
+	fakeClient := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment).Build()
+	fakeCluster := &Cluster{uncachedClient: fakeClient, connected: true}
+
+	actions := []Action{}
+
+	klient := ClientFor[*appsv1.Deployment, *appsv1.DeploymentList](ctx, fakeCluster, false, WithInterceptors(
+		func(ctx context.Context, op Operation, next func(ctx context.Context) error) error {
+			actions = append(actions, op.Action)
+			return next(ctx)
+		},
+	))
+
+	scale := &autoscalingv1.Scale{Spec: autoscalingv1.ScaleSpec{Replicas: 3}}
+	scale.Name = deployment.Name
+	scale.Namespace = deployment.Namespace
+
+	if err := klient.UpdateScale(ctx, scale, DryRun()); err != nil {
+		t.Fatalf("expected no error updating scale, got: %v", err)
+	}
+
+	// reading the original scale to log the dry-run diff must not be intercepted as a separate get
+	if expected := []Action{ActionUpdate}; !slices.Equal(actions, expected) {
+		t.Fatalf("expected intercepted actions to be %v, got: %v", expected, actions)
+	}
+}
+
+func TestTags(t *testing.T) {
+	hook := &Hook[*TaggedTestResource]{
+		DefaulterFunc: func(ctx context.Context, resource *TaggedTestResource) error {
+			if resource.Spec.Replicas == nil || *resource.Spec.Replicas != 3 {
+				t.Fatalf("expected tag defaults to be applied before the defaulter func, got: %+v", resource.Spec)
+			}
+			return nil
+		},
+		groupKind: schema.GroupKind{Group: "kapi-test.comradequinn.github.io", Kind: "TaggedTestResource"},
+	}
+
+	resource := &TaggedTestResource{Spec: TaggedTestResourceSpec{Tier: "gold"}}
+	resource.Name = "tagged-test-resource"
+
+	if err := hook.Default(ctx, resource); err != nil {
+		t.Fatalf("expected no error applying defaults, got: %v", err)
+	}
+
+	if *resource.Spec.Replicas != 3 || resource.Spec.Options == nil || resource.Spec.Options.Enabled == nil || !*resource.Spec.Options.Enabled {
+		t.Fatalf("expected defaults to be applied, got: %+v", resource.Spec)
+	}
+
+	// explicit zero values are not overwritten by defaults
+	resource.Spec.Options.Enabled = ptrTo(false)
+
+	if err := hook.Default(ctx, resource); err != nil || *resource.Spec.Options.Enabled {
+		t.Fatalf("expected explicit false not to be defaulted, got: %v, %+v", err, resource.Spec.Options)
+	}
+
+	if err := validateTags(reflect.TypeOf(struct {
+		Replicas int32 `json:"replicas" kapi:"default=3"`
+	}{})); err == nil {
+		t.Fatalf("expected error declaring a default on a non-pointer scalar field")
+	}
+
+	if _, err := hook.ValidateCreate(ctx, resource); err != nil {
+		t.Fatalf("expected no error validating valid resource, got: %v", err)
+	}
+
+	resource.Spec = TaggedTestResourceSpec{Replicas: ptrTo(int32(11)), Name: "Invalid", Items: []TaggedTestItem{{Key: "a"}, {}, {Key: "c"}}}
+
+	_, err := hook.ValidateCreate(ctx, resource)
+
+	if !IsInvalid(err) {
+		t.Fatalf("expected invalid error validating invalid resource, got: %v", err)
+	}
+
+	causes := []string{}
+
+	for cause := range slices.Values(err.(*apierrors.StatusError).ErrStatus.Details.Causes) {
+		causes = append(causes, cause.Field)
+	}
+
+	if expected := []string{"spec.replicas", "spec.tier", "spec.name", "spec.items", "spec.items[1].key"}; !slices.Equal(causes, expected) {
+		t.Fatalf("expected causes for fields %v, got: %v", expected, causes)
+	}
+
+	spec, err := schemaFor(reflect.TypeOf(TaggedTestResourceSpec{}))
+
+	if err != nil {
+		t.Fatalf("expected no error generating schema, got: %v", err)
+	}
+
+	replicas, tier, items := spec.Properties["replicas"], spec.Properties["tier"], spec.Properties["items"]
+
+	if string(replicas.Default.Raw) != "3" || *replicas.Minimum != 1 || *replicas.Maximum != 10 || len(tier.Enum) != 3 || string(tier.Enum[0].Raw) != `"bronze"` || *items.MaxItems != 2 {
+		t.Fatalf("expected schema to include tag rules, got: %+v", spec)
+	}
+
+	if !slices.Contains(spec.Required, "tier") || spec.Properties["name"].Pattern == "" {
+		t.Fatalf("expected schema to require tier and include pattern, got: %+v", spec)
+	}
+}
+
+func TestCEL(t *testing.T) {
+	resource := &CELTestResource{Spec: CELTestResourceSpec{MinReplicas: 1, MaxReplicas: 2, Region: "eu", Prefix: "cel-test"}}
+
+	if errs, err := ValidateCEL(nil, resource); err != nil || len(errs) > 0 {
+		t.Fatalf("expected no errors validating valid resource, got: %v, %v", errs, err)
+	}
+
+	updated := resource.DeepCopyObject().(*CELTestResource)
+	updated.Spec = CELTestResourceSpec{MinReplicas: 3, MaxReplicas: 2, Region: "us", Prefix: "test"}
+
+	errs, err := ValidateCEL(resource, updated)
+
+	if err != nil {
+		t.Fatalf("expected no error evaluating rules, got: %v", err)
+	}
+
+	fields := []string{}
+
+	for e := range slices.Values(errs) {
+		fields = append(fields, e.Field+": "+e.Detail)
+	}
+
+	if expected := []string{"spec.maxReplicas: must not be less than minReplicas", "spec.prefix: must start with cel-", "spec.region: field is immutable"}; !slices.Equal(fields, expected) {
+		t.Fatalf("expected errors %v, got: %v", expected, fields)
+	}
+
+	if errs, _ := ValidateCEL(nil, updated); len(errs) != 2 {
+		t.Fatalf("expected transition rules to be skipped on create, got: %v", errs)
+	}
+
+	schema, err := schemaFor(reflect.TypeOf(CELTestResource{}))
+
+	if err != nil {
+		t.Fatalf("expected no error generating schema, got: %v", err)
+	}
+
+	spec := schema.Properties["spec"]
+
+	if len(spec.XValidations) != 1 || spec.Properties["region"].XValidations[0].Rule != "self == oldSelf" || spec.Properties["prefix"].XValidations[0].Rule != "self.startsWith('cel-')" {
+		t.Fatalf("expected schema to include cel rules, got: %+v", spec)
+	}
+
+	if !declaresCELRules(reflect.TypeOf(&CELTestResource{})) || declaresCELRules(reflect.TypeOf(&TestResource{})) {
+		t.Fatalf("expected cel rules to be detected only where declared")
+	}
+
+	// hooks for types that declare cel rules must fail where no schema can be generated to evaluate them, rather than leave them unenforced
+	unschematised := reflect.TypeOf(struct {
+		Counts map[int]string `json:"counts" cel:"size(self) > 0"`
+	}{})
+
+	if _, err := schemaFor(unschematised); err == nil || !declaresCELRules(unschematised) {
+		t.Fatalf("expected a type declaring cel rules for which no schema can be generated, got: %v", err)
+	}
+}
+
+func TestConversion(t *testing.T) {
+	crds, err := clusterConfig.CustomResourceDefinitions()
+
+	if err != nil {
+		t.Fatalf("expected no error generating custom resource definitions, got: %v", err)
+	}
+
+	i := slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "VersionedTestResource"
+	})
+
+	if i == -1 {
+		t.Fatalf("expected custom resource definition for VersionedTestResource, got: %+v", crds)
+	}
+
+	versions := crds[i].Spec.Versions
+
+	if len(versions) != 2 || versions[0].Name != "v1" || !versions[0].Storage || versions[1].Name != "v1alpha1" || versions[1].Storage {
+		t.Fatalf("expected v1 storage version and v1alpha1 version, got: %+v", versions)
+	}
+
+	if crds[i].Spec.Conversion == nil || crds[i].Spec.Conversion.Strategy != apiextensionsv1.NoneConverter {
+		t.Fatalf("expected none conversion strategy without a webhook service, got: %+v", crds[i].Spec.Conversion)
+	}
+
+	cfg := clusterConfig
+	cfg.Webhooks = WebhookConfig{ServiceName: "kapi-test", ServiceNamespace: testNamespace}
+
+	if crds, err = cfg.CustomResourceDefinitions(); err != nil || crds[i].Spec.Conversion.Strategy != apiextensionsv1.WebhookConverter || *crds[i].Spec.Conversion.Webhook.ClientConfig.Service.Path != "/convert" {
+		t.Fatalf("expected webhook conversion strategy with a webhook service, got: %+v, %v", crds[i].Spec.Conversion, err)
+	}
+
+	review := apiextensionsv1.ConversionReview{
+		Request: &apiextensionsv1.ConversionRequest{
+			UID:               "test-uid",
+			DesiredAPIVersion: "kapi-test.comradequinn.github.io/v1",
+			Objects: []runtime.RawExtension{{
+				Raw: []byte(`{"apiVersion":"kapi-test.comradequinn.github.io/v1alpha1","kind":"VersionedTestResource","metadata":{"name":"versioned","labels":{"a":"b"}},"spec":{"size":"3"}}`),
+			}},
+		},
+	}
+
+	body, _ := json.Marshal(review)
+	recorder := httptest.NewRecorder()
+
+	cluster.conversions.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body)))
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), &review); err != nil || review.Response == nil || review.Response.Result.Status != metav1.StatusSuccess || review.Response.UID != "test-uid" || review.Kind != "ConversionReview" {
+		t.Fatalf("expected successful conversion review response, got: %s, %v", recorder.Body.String(), err)
+	}
+
+	converted := &VersionedTestResource{}
+
+	if err := json.Unmarshal(review.Response.ConvertedObjects[0].Raw, converted); err != nil || converted.APIVersion != "kapi-test.comradequinn.github.io/v1" || converted.Spec.Replicas != 3 || converted.Name != "versioned" || converted.Labels["a"] != "b" {
+		t.Fatalf("expected resource converted to v1 with metadata retained, got: %+v, %v", converted, err)
+	}
+}
+
+func TestPrinterColumns(t *testing.T) {
+	crds, err := clusterConfig.CustomResourceDefinitions()
+
+	if err != nil {
+		t.Fatalf("expected no error generating custom resource definitions, got: %v", err)
+	}
+
+	i := slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "ScalableTestResource"
+	})
+
+	if names := crds[i].Spec.Names; !slices.Equal(names.ShortNames, []string{"str"}) || !slices.Equal(names.Categories, []string{"kapi-test"}) {
+		t.Fatalf("expected short names and categories, got: %+v", names)
+	}
+
+	expected := []apiextensionsv1.CustomResourceColumnDefinition{
+		{Name: "Desired", Type: "integer", JSONPath: ".spec.replicas"},
+		{Name: "Current", Type: "integer", JSONPath: ".status.replicas", Priority: 1},
+		{Name: "Selector", Type: "string", JSONPath: ".spec.selector", Priority: 1},
+		{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+
+	if columns := crds[i].Spec.Versions[0].AdditionalPrinterColumns; !reflect.DeepEqual(columns, expected) {
+		t.Fatalf("expected printer columns %+v, got: %+v", expected, columns)
+	}
+
+	i = slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "TestResource"
+	})
+
+	if columns := crds[i].Spec.Versions[0].AdditionalPrinterColumns; columns != nil {
+		t.Fatalf("expected no printer columns for kind without columns, got: %+v", columns)
+	}
+}
+
+func TestRegistration(t *testing.T) {
+	crd := CRDs{
+		APIGroup:   "kapi-test.comradequinn.github.io",
+		APIVersion: "v1",
+		Types:      []KindType{&TestResource{}, &TestResourceList{}, &corev1.ConfigMap{}},
+	}
+
+	if kinds := slices.Sorted(maps.Keys(crd.kinds())); !slices.Equal(kinds, []string{"ConfigMap", "TestResource", "TestResourceList"}) {
+		t.Fatalf("expected kind names derived from types, got: %v", kinds)
+	}
+
+	_, err := NewCluster(ctx, ClusterConfig{
+		CRDs: []CRDs{
+			{
+				APIGroup:   "kapi-test.comradequinn.github.io",
+				APIVersion: "v1",
+				Kinds: map[string]KindType{
+					"TestResource":             &TestResource{},
+					"TestResourceItems":        &TestResourceList{},
+					"ScalableTestResource":     &ScalableTestResource{},
+					"ScalableTestResourceList": &TestResourceList{},
+					"NonPointer":               nonPointerTestKind{},
+					"Mismatched":               &corev1.ConfigMap{},
+					"MismatchedList":           &corev1.ConfigMapList{},
+				},
+				Options: map[string]KindOptions{
+					"Unregistered": {},
+				},
+			},
+		},
+	})
+
+	expected := []string{
+		"kind ScalableTestResourceList has type",
+		"kind NonPointer has type kapi.nonPointerTestKind which is not a pointer to a struct",
+		"kind TestResource has no TestResourceList kind",
+		"kind TestResourceItems has list type",
+		"options are defined for kind Unregistered",
+		"kind Mismatched has type *v1.ConfigMap whose kind name is ConfigMap",
+		"kind MismatchedList has type *v1.ConfigMapList whose kind name is ConfigMapList",
+	}
+
+	for e := range slices.Values(expected) {
+		if err == nil || !strings.Contains(err.Error(), e) {
+			t.Fatalf("expected error containing %q, got: %v", e, err)
+		}
+	}
+
+	// the kind name of an alias of CustomResource is not derived from its spec type where it is registered in kinds, so its spec type may be
+	// named differently to the kind
+	err = ClusterConfig{
+		CRDs: []CRDs{
+			{
+				APIGroup:   "kapi-test.comradequinn.github.io",
+				APIVersion: "v1",
+				Kinds: map[string]KindType{
+					"Widget":     &CustomResource[WidgetTestConfig, FieldUndefined, FieldUndefined]{},
+					"WidgetList": &CustomResourceList[*CustomResource[WidgetTestConfig, FieldUndefined, FieldUndefined]]{},
+					"Gadget":     &ScalableTestResource{},
+					"GadgetList": &ScalableTestResourceList{},
+				},
+			},
+		},
+	}.validate()
+
+	if err != nil {
+		t.Fatalf("expected no error registering aliases of custom resource with differently named spec types, got: %v", err)
+	}
+}
+
+func TestClusterScoped(t *testing.T) {
+	if _, err := byObject(map[KindType]CacheConfig{&ClusterTestResource{}: {Namespaces: []string{testNamespace}}}, clusterConfig.CRDs); err == nil {
+		t.Fatalf("expected error configuring namespaces for the cache of a cluster-scoped kind")
+	}
+
+	crds, err := clusterConfig.CustomResourceDefinitions()
+
+	if err != nil {
+		t.Fatalf("expected no error generating custom resource definitions, got: %v", err)
+	}
+
+	i := slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "ClusterTestResource"
+	})
+
+	if i == -1 || crds[i].Spec.Scope != apiextensionsv1.ClusterScoped {
+		t.Fatalf("expected cluster-scoped custom resource definition for ClusterTestResource, got: %+v", crds)
+	}
+
+	crdKlient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, true)
+
+	if err := crdKlient.Create(ctx, crds[i]); err != nil {
+		t.Fatalf("expected no error creating custom resource definition, got: %v", err)
+	}
+
+	{ // wait for the CRD to be established for up to 30 seconds (expected time should be <1s)
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		if _, err := crdKlient.WaitForByName(waitCtx, crds[i].Name, CRDEstablished); err != nil {
+			t.Fatalf("expected custom resource definition to be established, got: %v", err)
+		}
+	}
+
+	// the cache is limited to the test namespace, so this verifies cluster-scoped kinds are cached across the cluster
+	klient := For[*ClusterTestResource](ctx, cluster, true, WithReadYourWrites(time.Second*5))
+
+	resource := &ClusterTestResource{Spec: ClusterTestResourceSpec{Tier: "gold"}}
+	resource.Name = "cluster-test-resource"
+
+	if err := klient.Create(ctx, resource); err != nil {
+		t.Fatalf("expected no error creating cluster-scoped test resource, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, resource)
+
+	actual, err := klient.GetByName(ctx, resource.Name)
+
+	if err != nil || actual.Spec.Tier != "gold" {
+		t.Fatalf("expected cluster-scoped test resource with tier gold, got: %+v, %v", actual, err)
+	}
+
+	resources, err := klient.List(ctx)
+
+	if err != nil || len(resources) != 1 {
+		t.Fatalf("expected list of 1 cluster-scoped test resource, got: %+v, %v", resources, err)
+	}
+}
+
+func TestCertRotation(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("expected no error adding client-go types to scheme, got: %v", err)
+	}
+
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("expected no error adding apiextensions types to scheme, got: %v", err)
+	}
+
+	webhooks := WebhookConfig{ServiceName: "kapi-test-webhooks", ServiceNamespace: testNamespace, GenerateCerts: true}
+	configuration := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: webhooks.ServiceName},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:         "kapi-test.comradequinn.github.io",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Name: webhooks.ServiceName, Namespace: webhooks.ServiceNamespace}},
+		}},
+	}
+
+	injectErr := fmt.Errorf("api server unavailable")
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configuration).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if injectErr != nil {
+				return injectErr
+			}
+
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	fakeCluster := &Cluster{uncachedClient: fakeClient, webhooks: webhooks, certDir: t.TempDir(), caBundle: []byte("previous")}
+	rotator := &certRotator{cluster: fakeCluster, cfg: webhooks.certsConfig()}
+
+	// where the ca bundle cannot be injected, the new certificate must not be served as the api server would be unable to verify it
+	if err := rotator.refresh(ctx); err == nil {
+		t.Fatalf("expected error refreshing certificates where the ca bundle cannot be injected")
+	}
+
+	if _, err := os.Stat(filepath.Join(fakeCluster.certDir, certs.CertFile)); !os.IsNotExist(err) {
+		t.Fatalf("expected no certificate to be written before the ca bundle is injected, got: %v", err)
+	}
+
+	if string(fakeCluster.caBundle) != "previous" {
+		t.Fatalf("expected ca bundle to be unchanged where it was not injected, got: %s", fakeCluster.caBundle)
+	}
+
+	injectErr = nil
+
+	if err := rotator.refresh(ctx); err != nil {
+		t.Fatalf("expected no error refreshing certificates, got: %v", err)
+	}
+
+	cert, err := os.ReadFile(filepath.Join(fakeCluster.certDir, certs.CertFile))
+
+	if err != nil || !bytes.Equal(cert, rotator.bundle.Cert) {
+		t.Fatalf("expected rotated certificate to be written, got: %v", err)
+	}
+
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(configuration), configuration); err != nil || !bytes.Equal(configuration.Webhooks[0].ClientConfig.CABundle, rotator.bundle.CABundle) {
+		t.Fatalf("expected ca bundle to be injected, got: %v", err)
+	}
+}
+
+func TestInstallWebhookConfigurationPreservesCABundle(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("expected no error adding client-go types to scheme, got: %v", err)
+	}
+
+	webhook := func(caBundle []byte, timeout int32) admissionregistrationv1.ValidatingWebhook {
+		return admissionregistrationv1.ValidatingWebhook{
+			Name:           "tagged.v1.kapi-test.comradequinn.github.io",
+			ClientConfig:   admissionregistrationv1.WebhookClientConfig{CABundle: caBundle},
+			TimeoutSeconds: ptrTo(timeout),
+		}
+	}
+
+	existing := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "kapi-test"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{webhook([]byte("injected"), 10)},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	fakeCluster := &Cluster{uncachedClient: fakeClient, connected: true}
+
+	// kapi does not generate the certificates, so the ca bundle injected by another controller must survive the update
+	updated := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "kapi-test"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{webhook(nil, 5)},
+	}
+
+	if err := installWebhookConfiguration[*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfigurationList](ctx, fakeCluster, updated, false); err != nil {
+		t.Fatalf("expected no error updating webhook configuration, got: %v", err)
+	}
+
+	actual := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(existing), actual); err != nil {
+		t.Fatalf("expected no error getting webhook configuration, got: %v", err)
+	}
+
+	if webhook := actual.Webhooks[0]; string(webhook.ClientConfig.CABundle) != "injected" || *webhook.TimeoutSeconds != 5 {
+		t.Fatalf("expected webhook to be updated with its injected ca bundle retained, got: %+v", webhook)
+	}
+}
+
+func TestWebhookConfigurations(t *testing.T) {
+	taggedGVK := schema.GroupVersionKind{Group: "kapi-test.comradequinn.github.io", Version: "v1", Kind: "TaggedTestResource"}
+	podGVK := corev1.SchemeGroupVersion.WithKind("Pod")
+
+	tagged := &Hook[*TaggedTestResource]{
+		ValidateDeleteFunc: func(ctx context.Context, resource *TaggedTestResource) (warnings []string, err error) {
+			return nil, nil
+		},
+		Options: HookOptions{IgnoreFailures: true, Timeout: time.Second * 5, NamespaceSelector: "environment in (test)"},
+	}
+
+	taggedRegistration, err := tagged.registration(taggedGVK, &TaggedTestResource{})
+
+	if err != nil {
+		t.Fatalf("expected no error registering tagged hook, got: %v", err)
+	}
+
+	pods := &Hook[*corev1.Pod]{
+		ValidateCreateFunc: func(ctx context.Context, resource *corev1.Pod) (warnings []string, err error) { return nil, nil },
+	}
+
+	podRegistration, err := pods.registration(podGVK, &corev1.Pod{})
+
+	if err != nil {
+		t.Fatalf("expected no error registering pod hook, got: %v", err)
+	}
+
+	if _, err := (&Hook[*corev1.Pod]{Options: HookOptions{Timeout: time.Minute}}).registration(podGVK, &corev1.Pod{}); err == nil {
+		t.Fatalf("expected error registering hook with timeout greater than 30 seconds")
+	}
+
+	if _, err := (&Hook[*corev1.Pod]{Options: HookOptions{NamespaceSelector: "environment in ("}}).registration(podGVK, &corev1.Pod{}); err == nil {
+		t.Fatalf("expected error registering hook with invalid namespace selector")
+	}
+
+	webhooks := WebhookConfig{ServiceName: "kapi-test", ServiceNamespace: testNamespace, InstallConfigurations: true}
+	caBundle := []byte("test-ca-bundle")
+
+	validating, mutating, err := webhooks.webhookConfigurations([]hookRegistration{taggedRegistration, podRegistration}, caBundle, func(gvk schema.GroupVersionKind) (string, error) {
+		return strings.ToLower(gvk.Kind) + "s", nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected no error generating webhook configurations, got: %v", err)
+	}
+
+	if validating.Name != "kapi-test" || len(validating.Webhooks) != 2 || mutating.Name != "kapi-test" || len(mutating.Webhooks) != 1 {
+		t.Fatalf("expected 2 validating and 1 mutating webhooks named after the service, got: %+v, %+v", validating, mutating)
+	}
+
+	operations := func(rules []admissionregistrationv1.RuleWithOperations) []admissionregistrationv1.OperationType {
+		return rules[0].Operations
+	}
+
+	// the tagged resource declares defaults and validation rules, so is mutated and validated on create and update, without a defaulter func
+	taggedWebhook, podWebhook, mutatingWebhook := validating.Webhooks[0], validating.Webhooks[1], mutating.Webhooks[0]
+
+	if taggedWebhook.Name != "taggedtestresource.v1.kapi-test.comradequinn.github.io" || *taggedWebhook.ClientConfig.Service.Path != "/validate-kapi-test-comradequinn-github-io-v1-taggedtestresource" {
+		t.Fatalf("expected validating webhook name and path derived from kind, got: %v, %v", taggedWebhook.Name, *taggedWebhook.ClientConfig.Service.Path)
+	}
+
+	if !slices.Equal(operations(taggedWebhook.Rules), []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update, admissionregistrationv1.Delete}) {
+		t.Fatalf("expected tagged resource to be validated on create, update and delete, got: %v", operations(taggedWebhook.Rules))
+	}
+
+	if *taggedWebhook.FailurePolicy != admissionregistrationv1.Ignore || *taggedWebhook.TimeoutSeconds != 5 || taggedWebhook.NamespaceSelector.MatchExpressions[0].Key != "environment" {
+		t.Fatalf("expected hook options to be applied, got: %+v", taggedWebhook)
+	}
+
+	if !bytes.Equal(taggedWebhook.ClientConfig.CABundle, caBundle) || *taggedWebhook.ClientConfig.Service.Port != 443 || taggedWebhook.Rules[0].Resources[0] != "taggedtestresources" {
+		t.Fatalf("expected ca bundle, default port and resource, got: %+v", taggedWebhook.ClientConfig)
+	}
+
+	if *mutatingWebhook.ClientConfig.Service.Path != "/mutate-kapi-test-comradequinn-github-io-v1-taggedtestresource" || !slices.Equal(operations(mutatingWebhook.Rules), []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}) {
+		t.Fatalf("expected tagged resource to be mutated on create and update, got: %+v", mutatingWebhook)
+	}
+
+	if podWebhook.Name != "pod.v1.core" || *podWebhook.ClientConfig.Service.Path != "/validate--v1-pod" || !slices.Equal(operations(podWebhook.Rules), []admissionregistrationv1.OperationType{admissionregistrationv1.Create}) {
+		t.Fatalf("expected pod to be validated on create only, got: %+v", podWebhook)
+	}
+
+	if *podWebhook.FailurePolicy != admissionregistrationv1.Fail || *podWebhook.TimeoutSeconds != 10 || podWebhook.NamespaceSelector != nil {
+		t.Fatalf("expected default hook options, got: %+v", podWebhook)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	errs := &FieldErrors{}
+
+	if errs.Err() != nil || errs.StatusError(schema.GroupKind{}, "") != nil {
+		t.Fatalf("expected no error where no field errors are recorded")
+	}
+
+	hook := &Hook[*TaggedTestResource]{
+		ValidateCreateFunc: func(ctx context.Context, resource *TaggedTestResource) (warnings []string, err error) {
+			errs := &FieldErrors{}
+
+			if resource.Spec.Tier == "gold" && *resource.Spec.Replicas < 5 {
+				errs.Invalid("spec.replicas", *resource.Spec.Replicas, "gold tier resources require at least 5 replicas")
+				errs.Forbidden("spec.tier", "gold tier is not available")
+			}
+
+			return nil, errs.Err()
+		},
+		groupKind: schema.GroupKind{Group: "kapi-test.comradequinn.github.io", Kind: "TaggedTestResource"},
+	}
+
+	resource := &TaggedTestResource{Spec: TaggedTestResourceSpec{Tier: "gold", Replicas: ptrTo(int32(3))}}
+	resource.Name = "field-errors-test-resource"
+
+	_, err := hook.ValidateCreate(ctx, resource)
+	invalidErr, ok := AsInvalidError(err)
+
+	if !ok || !IsInvalid(err) {
+		t.Fatalf("expected invalid error from field errors returned by hook func, got: %v", err)
+	}
+
+	expected := []FieldCause{
+		{Field: "spec.replicas", Type: "FieldValueInvalid", Message: "Invalid value: 3: gold tier resources require at least 5 replicas"},
+		{Field: "spec.tier", Type: "FieldValueForbidden", Message: "Forbidden: gold tier is not available"},
+	}
+
+	if !slices.Equal(invalidErr.Causes, expected) {
+		t.Fatalf("expected causes %+v, got: %+v", expected, invalidErr.Causes)
+	}
+
+	if !strings.Contains(invalidErr.Message, "TaggedTestResource.kapi-test.comradequinn.github.io \"field-errors-test-resource\" is invalid") {
+		t.Fatalf("expected message to identify the resource, got: %v", invalidErr.Message)
+	}
+
+	// tag rules are reported in the same way as field errors returned by hook funcs
+	resource.Spec.Tier = "platinum"
+
+	_, err = hook.ValidateCreate(ctx, resource)
+
+	if invalidErr, ok := AsInvalidError(err); !ok || len(invalidErr.Causes) != 1 || invalidErr.Causes[0].Field != "spec.tier" || invalidErr.Causes[0].Type != "FieldValueNotSupported" {
+		t.Fatalf("expected invalid error for unsupported tier, got: %v", err)
+	}
+
+	errs.Add(field.Required(field.NewPath("spec", "name"), "a name is required"))
+	errs.NotSupported("spec.tier", "platinum", "bronze", "silver", "gold")
+	errs.Duplicate("spec.items[1].key", "a")
+	errs.Required("spec.items[2].key", "a key is required")
+
+	if errs.Len() != 4 || !strings.Contains(errs.Error(), `spec.items[1].key: Duplicate value: "a"`) {
+		t.Fatalf("expected 4 field errors, got: %v", errs)
+	}
+}
+
+func TestAdmissionRequest(t *testing.T) {
+	if _, ok := AdmissionRequestFrom(ctx); ok {
+		t.Fatalf("expected no admission request outside of a hook")
+	}
+
+	if err := AllowServiceAccounts(ctx, "kapi-test/*"); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error where the requester is unknown, got: %v", err)
+	}
+
+	var actual AdmissionRequest
+
+	hook := &Hook[*TestResource]{
+		ValidateCreateFunc: func(ctx context.Context, resource *TestResource) (warnings []string, err error) {
+			actual, _ = AdmissionRequestFrom(ctx)
+
+			if resource.Spec.TestData == "gold" && !actual.InGroup("platform-team") {
+				return nil, AllowServiceAccounts(ctx, "kapi-test/platform-operator")
+			}
+
+			return nil, DenyServiceAccounts(ctx, "kube-system/*")
+		},
+	}
+
+	admissionCtx := func(user string, groups ...string) context.Context {
+		return admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:         "test-uid",
+			Operation:   admissionv1.Create,
+			Namespace:   testNamespace,
+			Name:        "admission-test-resource",
+			SubResource: "status",
+			Resource:    metav1.GroupVersionResource{Group: "kapi-test.comradequinn.github.io", Version: "v1", Resource: "testresources"},
+			UserInfo:    authenticationv1.UserInfo{Username: user, Groups: groups},
+			DryRun:      ptrTo(true),
+		}})
+	}
+
+	resource := &TestResource{Spec: TestResourceSpec{TestData: "silver"}}
+	resource.Name = "admission-test-resource"
+
+	if _, err := hook.ValidateCreate(admissionCtx("jane", "developers"), resource); err != nil {
+		t.Fatalf("expected no error validating request from user, got: %v", err)
+	}
+
+	if actual.UID != "test-uid" || actual.Operation != "CREATE" || actual.Namespace != testNamespace || actual.Subresource != "status" || actual.User != "jane" || !actual.DryRun || !actual.InGroup("developers") {
+		t.Fatalf("expected admission request details in hook ctx, got: %+v", actual)
+	}
+
+	if _, _, ok := actual.ServiceAccount(); ok {
+		t.Fatalf("expected request from user not to be from a service account")
+	}
+
+	if _, err := hook.ValidateCreate(admissionCtx("system:serviceaccount:kube-system:deployer"), resource); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error for denied service account, got: %v", err)
+	}
+
+	if namespace, name, ok := actual.ServiceAccount(); !ok || namespace != "kube-system" || name != "deployer" {
+		t.Fatalf("expected request from kube-system/deployer service account, got: %v/%v, %v", namespace, name, ok)
+	}
+
+	resource.Spec.TestData = "gold"
+
+	if _, err := hook.ValidateCreate(admissionCtx("jane", "developers"), resource); !IsForbidden(err) {
+		t.Fatalf("expected forbidden error for user outside of platform team, got: %v", err)
+	}
+
+	for user, groups := range map[string][]string{"system:serviceaccount:kapi-test:platform-operator": nil, "john": {"platform-team"}} {
+		if _, err := hook.ValidateCreate(admissionCtx(user, groups...), resource); err != nil {
+			t.Fatalf("expected no error validating request from %v, got: %v", user, err)
+		}
+	}
+}
+
+func TestResourceClient(t *testing.T) {
+	klient := For[*corev1.ConfigMap](ctx, cluster, false)
+
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "resource-client-data"
+	cfgMap.Namespace = testNamespace
+
+	if err := klient.Create(ctx, cfgMap); err != nil {
+		t.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	defer klient.Delete(ctx, cfgMap)
+
+	cfgMaps, err := klient.List(ctx)
+
+	if err != nil {
+		t.Fatalf("expected no error listing configmaps, got: %v", err)
+	}
+
+	if !slices.ContainsFunc(cfgMaps, func(c *corev1.ConfigMap) bool { return c.Name == cfgMap.Name }) {
+		t.Fatalf("expected configmap %v in list, got: %+v", cfgMap.Name, cfgMaps)
+	}
+
+	crds, err := clusterConfig.CustomResourceDefinitions()
+
+	if err != nil {
+		t.Fatalf("expected no error generating custom resource definitions, got: %v", err)
+	}
+
+	i := slices.IndexFunc(crds, func(crd *apiextensionsv1.CustomResourceDefinition) bool {
+		return crd.Spec.Names.Kind == "TestResource"
+	})
+
+	if i == -1 {
+		t.Fatalf("expected custom resource definition for TestResource, got: %+v", crds)
+	}
+
+	crdKlient := ClientFor[*apiextensionsv1.CustomResourceDefinition, *apiextensionsv1.CustomResourceDefinitionList](ctx, cluster, true)
+
+	// the crd is also installed by TestCRD, so it may already exist
+	if err := crdKlient.Create(ctx, crds[i]); err != nil && !IsAlreadyExists(err) {
+		t.Fatalf("expected no error creating custom resource definition, got: %v", err)
+	}
+
+	{ // wait for the CRD to be established for up to 30 seconds (expected time should be <1s)
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second*30)
+		defer cancel()
+
+		if _, err := crdKlient.WaitForByName(waitCtx, crds[i].Name, CRDEstablished); err != nil {
+			t.Fatalf("expected custom resource definition to be established, got: %v", err)
+		}
+	}
+
+	if _, err := For[*TestResource](ctx, cluster, false).List(ctx); err != nil {
+		t.Fatalf("expected no error listing test resources, got: %v", err)
+	}
+
+	// the type is not registered with the cluster, so operations that require its list type must return an error rather than panic
+	unregistered := For[*CustomResource[FieldUndefined, FieldUndefined, FieldUndefined]](ctx, cluster, false)
+
+	if _, err := unregistered.List(ctx); err == nil {
+		t.Fatalf("expected error listing unregistered resources")
+	}
+
+	if _, err := unregistered.Watch(ctx, testNamespace); err == nil {
+		t.Fatalf("expected error watching unregistered resources")
+	}
+
+	if err := unregistered.WaitForDeletion(ctx, testNamespace, "unregistered"); err == nil {
+		t.Fatalf("expected error waiting for unregistered resource")
+	}
+}
+
+func TestResourceClientWithoutListKind(t *testing.T) {
+	// the kind is registered without a list kind, so its resources must be listed as unstructured and converted
+	gvk := schema.GroupVersionKind{Group: "unlisted.kapi-test.comradequinn.github.io", Version: "v1", Kind: "TestResource"}
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(gvk, &TestResource{})
+
+	resource := &TestResource{}
+	resource.SetGroupVersionKind(gvk)
+	resource.Name = "unlisted"
+	resource.Namespace = testNamespace
+	resource.Spec.TestData = "unlisted-data"
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(resource)
+
+	if err != nil {
+		t.Fatalf("expected no error converting resource, got: %v", err)
+	}
+
+	// the fake client requires a registered list kind to list typed resources, so it holds the resource as unstructured, as the api server would
+	fakeClient := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).WithObjects(&unstructured.Unstructured{Object: content}).Build()
+
+	newResourceList, resourceListType, err := listFor[*TestResource](scheme, &TestResource{})
+
+	if err != nil {
+		t.Fatalf("expected no error resolving list type, got: %v", err)
+	}
+
+	if resourceListType != "*unstructured.UnstructuredList" {
+		t.Fatalf("expected list type *unstructured.UnstructuredList, got: %v", resourceListType)
+	}
+
+	resourceList := newResourceList()
+
+	if err := fakeClient.List(ctx, resourceList, client.InNamespace(testNamespace)); err != nil {
+		t.Fatalf("expected no error listing resources, got: %v", err)
+	}
+
+	resources, err := items[*TestResource](resourceList)
+
+	if err != nil {
+		t.Fatalf("expected no error extracting resources, got: %v", err)
+	}
+
+	if len(resources) != 1 || resources[0].Name != resource.Name || resources[0].Spec.TestData != resource.Spec.TestData {
+		t.Fatalf("expected resource %v with data %v, got: %+v", resource.Name, resource.Spec.TestData, resources)
+	}
+}
+
+func BenchmarkKapiClientGet(b *testing.B) {
+	cfgMap := &corev1.ConfigMap{}
+	cfgMap.Name = "benchmark-data"
+	cfgMap.Namespace = testNamespace
+
+	if err := ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false).Create(ctx, cfgMap); err != nil {
+		b.Fatalf("expected no error creating configmap, got: %v", err)
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		benchmarkGet(b, ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, true), cfgMap)
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		benchmarkGet(b, ClientFor[*corev1.ConfigMap, *corev1.ConfigMapList](ctx, cluster, false), cfgMap)
+	})
+
+	b.Run("uncached-client-per-operation", func(b *testing.B) {
+		// reproduces the previous behaviour of uncached clients, where a new controller-runtime client was constructed for each operation
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for range b.N {
+			klient, err := client.New(cluster.manager.GetConfig(), client.Options{
+				Scheme: cluster.manager.GetScheme(),
+			})
+
+			if err != nil {
+				b.Fatalf("expected no error creating client, got: %v", err)
+			}
+
+			if err := klient.Get(ctx, client.ObjectKeyFromObject(cfgMap), &corev1.ConfigMap{}); err != nil {
+				b.Fatalf("expected no error getting configmap, got: %v", err)
+			}
+		}
+	})
+}
+
+func benchmarkGet(b *testing.B, klient *Client[*corev1.ConfigMap, *corev1.ConfigMapList], cfgMap *corev1.ConfigMap) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		if _, err := klient.Get(ctx, cfgMap.Namespace, cfgMap.Name); err != nil {
+			b.Fatalf("expected no error getting configmap, got: %v", err)
+		}
 	}
 }
 
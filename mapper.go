@@ -0,0 +1,83 @@
+package kapi
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type (
+	// crdMapper resolves the REST mappings of kinds defined in ClusterConfig.CRDs from their KindOptions, where they cannot be discovered
+	// from the cluster; for example before their CustomResourceDefinitions are installed.
+	//
+	// This allows the scope of kapi kinds to be known to the cache, and its config validated, independently of the state of the cluster
+	crdMapper struct {
+		meta.RESTMapper
+		mappings map[schema.GroupKind][]*meta.RESTMapping
+	}
+)
+
+func newCRDMapperProvider(crds []CRDs) func(cfg *rest.Config, httpClient *http.Client) (meta.RESTMapper, error) {
+	mappings := map[schema.GroupKind][]*meta.RESTMapping{}
+
+	for gk, kindVersions := range kindVersionsOf(crds) {
+		for kv := range slices.Values(kindVersions) {
+			gvk := gk.WithVersion(kv.crd.APIVersion)
+			scope := meta.RESTScopeNamespace
+
+			if kv.crd.Options[kv.kindName].ClusterScoped {
+				scope = meta.RESTScopeRoot
+			}
+
+			mappings[gk] = append(mappings[gk], &meta.RESTMapping{
+				Resource:         gvk.GroupVersion().WithResource(kv.crd.plural(kv.kindName)),
+				GroupVersionKind: gvk,
+				Scope:            scope,
+			})
+		}
+	}
+
+	return func(cfg *rest.Config, httpClient *http.Client) (meta.RESTMapper, error) {
+		mapper, err := apiutil.NewDynamicRESTMapper(cfg, httpClient)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &crdMapper{RESTMapper: mapper, mappings: mappings}, nil
+	}
+}
+
+func (m *crdMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	mapping, err := m.RESTMapper.RESTMapping(gk, versions...)
+
+	if err == nil || !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+
+	for crdMapping := range slices.Values(m.mappings[gk]) {
+		if len(versions) == 0 || slices.Contains(versions, crdMapping.GroupVersionKind.Version) {
+			return crdMapping, nil
+		}
+	}
+
+	return nil, err
+}
+
+// isClusterScoped returns true if the passed resource type is declared as cluster-scoped in the passed CRDs
+func isClusterScoped(crds []CRDs, kindType KindType) bool {
+	for crd := range slices.Values(crds) {
+		for kindName, crdKindType := range crd.kinds() {
+			if reflect.TypeOf(crdKindType) == reflect.TypeOf(kindType) {
+				return crd.Options[kindName].ClusterScoped
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package kapi
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MetadataClientFor returns a Client that operates only on the metadata of resources of the specified GroupVersionKind,
+// represented as metav1.PartialObjectMetadata; for example their names, labels and annotations.
+//
+// Where cache is true, only resource metadata is held in the cache. This significantly reduces memory use where large numbers
+// of resources are cached but only their metadata is of interest.
+//
+// Metadata clients support Get, List, Watch, WaitFor and Delete operations. As the full resource is not available, Create and Update
+// are not supported.
+func MetadataClientFor(ctx context.Context, cluster *Cluster, gvk schema.GroupVersionKind, cache bool, opts ...ClientOption) *Client[*metav1.PartialObjectMetadata, *metav1.PartialObjectMetadataList] {
+	return newClient(ctx, cluster, cache,
+		func() *metav1.PartialObjectMetadata {
+			resource := &metav1.PartialObjectMetadata{}
+			resource.SetGroupVersionKind(gvk)
+			return resource
+		},
+		func() *metav1.PartialObjectMetadataList {
+			resourceList := &metav1.PartialObjectMetadataList{}
+			resourceList.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			return resourceList
+		},
+		"metadata:"+gvk.String(),
+		"metadata:"+gvk.GroupVersion().WithKind(gvk.Kind+"List").String(),
+		opts,
+	)
+}
+
+// AddMetadataReconciler causes the specified ReconcilerFunc to be invoked whenever any resource of the specified GroupVersionKind
+// in the specified cluster is subject to a modification event: create, update or delete.
+//
+// It behaves as AddReconciler, except that only resource metadata is cached and passed to the ReconcilerFunc, as a metav1.PartialObjectMetadata.
+func AddMetadataReconciler(ctx context.Context, cluster *Cluster, gvk schema.GroupVersionKind, reconcilerFilterFunc ReconcilerFilterFunc, reconcilerFunc ReconcilerFunc[*metav1.PartialObjectMetadata]) error {
+	klient := MetadataClientFor(ctx, cluster, gvk, true)
+
+	return addReconciler(ctx, cluster, klient.newResource(), klient.resourceType, klient.Get, reconcilerFilterFunc, reconcilerFunc)
+}
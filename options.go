@@ -0,0 +1,149 @@
+package kapi
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// Option configures the behaviour of an individual Client operation.
+	//
+	// Options that are not relevant to the operation they are passed to are ignored by it.
+	Option interface {
+		apply(*options)
+	}
+	// ClientOption configures the behaviour of a Client for all of its operations
+	ClientOption func(*clientOptions)
+	// PropagationPolicy defines how the dependents of a resource are handled when it is deleted
+	PropagationPolicy string
+
+	optionFunc func(*options)
+
+	clientOptions struct {
+		readYourWritesTimeout time.Duration
+		interceptors          []Interceptor
+	}
+
+	deleteOption interface {
+		client.DeleteOption
+		client.DeleteAllOfOption
+	}
+
+	options struct {
+		subresources      []Subresource
+		propagationPolicy PropagationPolicy
+		gracePeriod       *time.Duration
+		preconditions     *metav1.Preconditions
+		wait              bool
+		bypassCache       bool
+		dryRun            bool
+	}
+)
+
+const (
+	// PropagationForeground deletes the dependents of a resource before the resource itself is removed
+	PropagationForeground = PropagationPolicy(metav1.DeletePropagationForeground)
+	// PropagationBackground removes the resource immediately and deletes its dependents in the background
+	PropagationBackground = PropagationPolicy(metav1.DeletePropagationBackground)
+	// PropagationOrphan removes the resource and leaves its dependents in place
+	PropagationOrphan = PropagationPolicy(metav1.DeletePropagationOrphan)
+)
+
+// WithPropagationPolicy sets how the dependents of a resource are handled when it is deleted
+func WithPropagationPolicy(policy PropagationPolicy) Option {
+	return optionFunc(func(o *options) {
+		o.propagationPolicy = policy
+	})
+}
+
+// WithGracePeriod sets the duration the resource is given to terminate gracefully when it is deleted.
+// A zero duration indicates the resource should be deleted immediately
+func WithGracePeriod(gracePeriod time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.gracePeriod = &gracePeriod
+	})
+}
+
+// WithPreconditions limits a delete to only proceed if the resource still has the specified UID and resourceVersion.
+// Either value can be left empty to skip that precondition
+func WithPreconditions(uid types.UID, resourceVersion string) Option {
+	return optionFunc(func(o *options) {
+		o.preconditions = &metav1.Preconditions{}
+
+		if uid != "" {
+			o.preconditions.UID = &uid
+		}
+
+		if resourceVersion != "" {
+			o.preconditions.ResourceVersion = &resourceVersion
+		}
+	})
+}
+
+// WithWait causes a delete to block until the deleted resources no longer exist on the cluster, or until the ctx is done.
+//
+// When combined with PropagationForeground, this includes waiting for the removal of any dependents
+func WithWait() Option {
+	return optionFunc(func(o *options) {
+		o.wait = true
+	})
+}
+
+// WithoutCache causes a read to be served directly by the cluster, rather than from the cache of a cached client
+func WithoutCache() Option {
+	return optionFunc(func(o *options) {
+		o.bypassCache = true
+	})
+}
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+func (s Subresource) apply(o *options) {
+	o.subresources = append(o.subresources, s)
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return o
+}
+
+// newWriteOptions returns the options for a write, which is additionally executed as a dry-run where the ctx is marked as such by DryRunReconciler
+func newWriteOptions(ctx context.Context, opts []Option) options {
+	o := newOptions(opts)
+	o.dryRun = o.dryRun || dryRunFrom(ctx)
+
+	return o
+}
+
+func (o options) deleteOptions() []deleteOption {
+	deleteOpts := make([]deleteOption, 0, 4)
+
+	if o.propagationPolicy != "" {
+		deleteOpts = append(deleteOpts, client.PropagationPolicy(o.propagationPolicy))
+	}
+
+	if o.gracePeriod != nil {
+		deleteOpts = append(deleteOpts, client.GracePeriodSeconds(int64(o.gracePeriod.Seconds())))
+	}
+
+	if o.preconditions != nil {
+		deleteOpts = append(deleteOpts, client.Preconditions(*o.preconditions))
+	}
+
+	if o.dryRun {
+		deleteOpts = append(deleteOpts, client.DryRunAll)
+	}
+
+	return deleteOpts
+}
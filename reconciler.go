@@ -3,14 +3,13 @@ package kapi
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"strconv"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -34,7 +33,8 @@ type (
 type (
 	reconciler[T client.Object] struct {
 		reconcilerFunc ReconcilerFunc[T]
-		client         *Client[T, *ListUndefined]
+		resourceType   string
+		get            func(ctx context.Context, namespace, name string, opts ...Option) (T, error)
 	}
 )
 
@@ -56,15 +56,25 @@ var (
 // modified and the type of the modification. The reconcilerFunc is only invoked if the eventFilterFunc returns true.
 //
 // A nil filterFunc value matches all events.
+//
+// Only one ReconcilerFunc can be added for each kind; an error is returned if one has already been added for the kind of T.
 func AddReconciler[T client.Object](ctx context.Context, cluster *Cluster, reconcilerFilterFunc ReconcilerFilterFunc, reconcilerFunc ReconcilerFunc[T]) error {
+	klient := For[T](ctx, cluster, true)
+
+	return addReconciler(ctx, cluster, klient.newResource(), klient.resourceType, klient.Get, reconcilerFilterFunc, reconcilerFunc)
+}
+
+func addReconciler[T client.Object](ctx context.Context, cluster *Cluster, resource T, resourceType string, get func(ctx context.Context, namespace, name string, opts ...Option) (T, error), reconcilerFilterFunc ReconcilerFilterFunc, reconcilerFunc ReconcilerFunc[T]) error {
 	if cluster.connected {
 		panic("kapi.add-reconciler must be called before kapi.cluster.connect")
 	}
 
-	var resource T
+	defer obs.MetricTimerFunc(ctx, "kapi_add_reconciler")("resource_type", resourceType)
+	obs.LogFunc(ctx, 3, "creating kapi.reconciler", "resource_type", resourceType)
 
-	defer obs.MetricTimerFunc(ctx, "kapi_add_reconciler")("resource_type", fmt.Sprintf("%T", resource))
-	obs.LogFunc(ctx, 3, "creating kapi.reconciler", "resource_type", fmt.Sprintf("%T", resource))
+	if cluster.dryRun {
+		reconcilerFunc = DryRunReconciler(reconcilerFunc)
+	}
 
 	if reconcilerFilterFunc == nil {
 		reconcilerFilterFunc = func(_ ResourceEventType, _ client.Object) bool { return true }
@@ -72,17 +82,26 @@ func AddReconciler[T client.Object](ctx context.Context, cluster *Cluster, recon
 
 	reconcilerFilterFuncWithLogging := func(e ResourceEventType, o client.Object) bool {
 		if !reconcilerFilterFunc(e, o) {
-			obs.LogFunc(ctx, 3, "kapi.reconciler.filterfunc dropped event", "resource_name", o.GetName(), "resource_namespace", o.GetNamespace(), "resource_type", fmt.Sprintf("%T", resource), "event_type", e.String())
+			obs.LogFunc(ctx, 3, "kapi.reconciler.filterfunc dropped event", "resource_name", o.GetName(), "resource_namespace", o.GetNamespace(), "resource_type", resourceType, "event_type", e.String())
 			return false
 		}
 
-		obs.LogFunc(ctx, 3, "kapi.reconciler.filterfunc allowed event", "resource_name", o.GetName(), "resource_namespace", o.GetNamespace(), "resource_type", fmt.Sprintf("%T", resource), "event_type", e.String())
+		obs.LogFunc(ctx, 3, "kapi.reconciler.filterfunc allowed event", "resource_name", o.GetName(), "resource_namespace", o.GetNamespace(), "resource_type", resourceType, "event_type", e.String())
 		return true
 	}
 
-	resource = reflect.New(reflect.TypeOf(resource).Elem()).Interface().(T)
+	gvk, err := apiutil.GVKForObject(resource, cluster.manager.GetScheme())
 
-	err := ctrl.NewControllerManagedBy(cluster.manager).
+	if err != nil {
+		return fmt.Errorf("unable to add kapi.reconciler for resource type %v as it is not registered with the kapi.cluster. built-in types are registered automatically, custom resources must be registered in ClusterConfig.CRDs. %v", resourceType, err)
+	}
+
+	// a controller's watches, such as those added by WatchByIndex, are resolved by kind, so only one reconciler may be added for each kind
+	if _, ok := cluster.controllers[gvk]; ok {
+		return fmt.Errorf("unable to add kapi.reconciler for resource type %v as a kapi.reconciler has already been added for kind %v", resourceType, gvk)
+	}
+
+	ctrlr, err := ctrl.NewControllerManagedBy(cluster.manager).
 		For(resource).
 		WithEventFilter(predicate.Funcs{
 			CreateFunc: func(e event.CreateEvent) bool {
@@ -95,16 +114,19 @@ func AddReconciler[T client.Object](ctx context.Context, cluster *Cluster, recon
 				return reconcilerFilterFuncWithLogging(ResourceEventTypeDeleted, e.Object)
 			},
 		}).
-		Complete(&reconciler[T]{
+		Build(&reconciler[T]{
 			reconcilerFunc: reconcilerFunc,
-			client:         ClientFor[T, *ListUndefined](ctx, cluster, true),
+			resourceType:   resourceType,
+			get:            get,
 		})
 
 	if err != nil {
 		return fmt.Errorf("unable to configure kapi.reconciler. %v", err)
 	}
 
-	obs.LogFunc(ctx, 3, "configured kapi.reconciler", "resource_type", fmt.Sprintf("%T", resource))
+	cluster.controllers[gvk] = ctrlr
+
+	obs.LogFunc(ctx, 3, "configured kapi.reconciler", "resource_type", resourceType)
 
 	return nil
 }
@@ -117,25 +139,25 @@ func (r *reconciler[T]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		err      error
 	)
 
-	defer obs.MetricTimerFunc(ctx, "kapi_reconcile")("resource_type", fmt.Sprintf("%T", resource))
+	defer obs.MetricTimerFunc(ctx, "kapi_reconcile")("resource_type", r.resourceType)
 
 	evt := ReconcileEventTypeCreatedOrUpdated
 
-	if resource, err = r.client.Get(ctx, req.NamespacedName.Namespace, req.NamespacedName.Name); err != nil {
-		if !apierrors.IsNotFound(err) {
-			obs.LogFunc(ctx, 0, "kapi.reconciler invoked for invalid resource", "error", err, "resource_name", req.NamespacedName.String(), "resource_type", fmt.Sprintf("%T", resource))
+	if resource, err = r.get(ctx, req.NamespacedName.Namespace, req.NamespacedName.Name); err != nil {
+		if !IsNotFound(err) {
+			obs.LogFunc(ctx, 0, "kapi.reconciler invoked for invalid resource", "error", err, "resource_name", req.NamespacedName.String(), "resource_type", r.resourceType)
 			return ctrl.Result{}, err
 		}
 
 		evt = ReconcileEventTypeDeleted
 	}
 
-	obs.LogFunc(ctx, 1, "kapi.reconciler invoked", "type", "kapi_reconciler_summary", "resource_name", req.NamespacedName.String(), "resource_type", fmt.Sprintf("%T", resource), "event_type", evt.String())
-	obs.LogFunc(ctx, 3, "kapi.reconciler invoked", "type", "kapi_reconciler_trace", "resource_name", req.NamespacedName.String(), "resource_type", fmt.Sprintf("%T", resource), "resource", fmt.Sprintf("%+v", resource), "event_type", evt.String())
+	obs.LogFunc(ctx, 1, "kapi.reconciler invoked", "type", "kapi_reconciler_summary", "resource_name", req.NamespacedName.String(), "resource_type", r.resourceType, "event_type", evt.String())
+	obs.LogFunc(ctx, 3, "kapi.reconciler invoked", "type", "kapi_reconciler_trace", "resource_name", req.NamespacedName.String(), "resource_type", r.resourceType, "resource", fmt.Sprintf("%+v", resource), "event_type", evt.String())
 
 	if err := r.reconcilerFunc(ctx, evt, resource); err != nil {
-		obs.LogFunc(ctx, 0, "kapi.reconciler unable to invoke reconciler-func", "error", err, "resource_name", req.NamespacedName.String(), "resource_type", fmt.Sprintf("%T", resource), "event_type", evt)
-		return ctrl.Result{}, fmt.Errorf("unable to execute configured reconcilerfunc. %v", err)
+		obs.LogFunc(ctx, 0, "kapi.reconciler unable to invoke reconciler-func", "error", err, "resource_name", req.NamespacedName.String(), "resource_type", r.resourceType, "event_type", evt)
+		return ctrl.Result{}, fmt.Errorf("unable to execute configured reconcilerfunc. %w", err)
 	}
 
 	return ctrl.Result{}, nil
@@ -0,0 +1,198 @@
+package kapi
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	kapiPkgPath = reflect.TypeOf(Cluster{}).PkgPath()
+)
+
+// kinds returns the kinds of the CRDs keyed by kind name; those defined in Kinds, along with those defined in Types keyed by the
+// names derived from their Go types. Types whose names cannot be derived are omitted; these are reported by ClusterConfig.validate
+func (crd CRDs) kinds() map[string]KindType {
+	if len(crd.Types) == 0 {
+		return crd.Kinds
+	}
+
+	kinds := maps.Clone(crd.Kinds)
+
+	if kinds == nil {
+		kinds = map[string]KindType{}
+	}
+
+	for kindType := range slices.Values(crd.Types) {
+		if kindName, err := kindNameOf(kindType); err == nil {
+			kinds[kindName] = kindType
+		}
+	}
+
+	return kinds
+}
+
+// kindNameOf derives the kind name of a Go type. This is the name of the type itself or, for an alias of CustomResource, the name of its Spec
+// type without the `Spec` suffix; for example `Example` for `kapi.CustomResource[ExampleSpec, ...]`. The kind name of a list type is that of
+// its items with a `List` suffix
+func kindNameOf(kindType KindType) (string, error) {
+	t := reflect.TypeOf(kindType)
+
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return "", fmt.Errorf("type %T is not a pointer to a struct", kindType)
+	}
+
+	t = t.Elem()
+	name, _, generic := strings.Cut(t.Name(), "[")
+
+	switch {
+	case name == "":
+		return "", fmt.Errorf("unable to derive kind name of unnamed type %T", kindType)
+	case !generic:
+		return name, nil
+	case t.PkgPath() == kapiPkgPath && name == "CustomResource":
+		specField, _ := t.FieldByName("Spec")
+		spec := specField.Type
+
+		for spec.Kind() == reflect.Pointer {
+			spec = spec.Elem()
+		}
+
+		if specName := spec.Name(); strings.HasSuffix(specName, "Spec") && specName != "Spec" && !strings.Contains(specName, "[") {
+			return strings.TrimSuffix(specName, "Spec"), nil
+		}
+
+		return "", fmt.Errorf("unable to derive kind name of %T from its spec type %v. the spec type must be named <Kind>Spec", kindType, spec)
+	case t.PkgPath() == kapiPkgPath && name == "CustomResourceList":
+		items, _ := t.FieldByName("Items")
+
+		if items.Type.Elem().Kind() != reflect.Pointer {
+			return "", fmt.Errorf("unable to derive kind name of %T as its items are not pointers", kindType)
+		}
+
+		kindName, err := kindNameOf(reflect.New(items.Type.Elem().Elem()).Interface().(KindType))
+
+		if err != nil {
+			return "", err
+		}
+
+		return kindName + "List", nil
+	default:
+		return "", fmt.Errorf("unable to derive kind name of generic type %T", kindType)
+	}
+}
+
+// validate returns an error describing each invalid registration in the CRDs of the ClusterConfig, or nil if all are valid
+func (cfg ClusterConfig) validate() error {
+	errs := []error{}
+
+	for i, crd := range cfg.CRDs {
+		invalid := func(format string, a ...any) {
+			errs = append(errs, fmt.Errorf("crds[%v] %v/%v: %v", i, crd.APIGroup, crd.APIVersion, fmt.Sprintf(format, a...)))
+		}
+
+		if crd.APIGroup == "" {
+			invalid("an api group is required")
+		}
+
+		if crd.APIVersion == "" {
+			invalid("an api version is required")
+		}
+
+		if len(crd.Kinds) == 0 && len(crd.Types) == 0 {
+			invalid("no kinds or types are defined")
+		}
+
+		for kindType := range slices.Values(crd.Types) {
+			kindName, err := kindNameOf(kindType)
+
+			if err != nil {
+				invalid("%v", err)
+				continue
+			}
+
+			if _, ok := crd.Kinds[kindName]; ok {
+				invalid("kind %v is defined in both kinds and types", kindName)
+			}
+		}
+
+		kinds := crd.kinds()
+		kindNames := map[reflect.Type]string{}
+
+		for _, kindName := range slices.Sorted(maps.Keys(kinds)) {
+			kindType := kinds[kindName]
+			t := reflect.TypeOf(kindType)
+
+			switch {
+			case kindName == "":
+				invalid("a kind name is required for type %T. add it to types to derive its name from the type", kindType)
+				continue
+			case kindType == nil:
+				invalid("kind %v has a nil type", kindName)
+				continue
+			case t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct:
+				invalid("kind %v has type %T which is not a pointer to a struct. register a pointer, such as &%v{}", kindName, kindType, t.Name())
+				continue
+			}
+
+			// only the name of a named, non-generic, type is certain to be its kind name; that of an alias of CustomResource is not compared as
+			// its spec type may be named differently to the kind
+			if typeName := t.Elem().Name(); typeName != "" && !strings.Contains(typeName, "[") && typeName != kindName {
+				invalid("kind %v has type %T whose kind name is %v. register it as %v or add it to types", kindName, kindType, typeName, typeName)
+			}
+
+			if other, ok := kindNames[t]; ok {
+				invalid("kinds %v and %v have the same type %T. each kind must have a distinct type", other, kindName, kindType)
+			}
+
+			kindNames[t] = kindName
+
+			if _, ok := kindType.(client.ObjectList); ok {
+				if !strings.HasSuffix(kindName, "List") {
+					invalid("kind %v has list type %T. list types must be registered as <Kind>List", kindName, kindType)
+				} else if item, ok := kinds[strings.TrimSuffix(kindName, "List")]; ok && listItemType(t) != reflect.TypeOf(item) {
+					invalid("kind %v has type %T whose items are not of type %T, the type of kind %v", kindName, kindType, item, strings.TrimSuffix(kindName, "List"))
+				}
+
+				continue
+			}
+
+			if _, ok := kindType.(client.Object); !ok {
+				invalid("kind %v has type %T which does not implement client.Object. embed kapi.CustomResource to implement it", kindName, kindType)
+				continue
+			}
+
+			if _, ok := kinds[kindName+"List"]; !ok {
+				invalid("kind %v has no %vList kind. register a list type, such as &kapi.CustomResourceList[%T]{}, as %vList", kindName, kindName, kindType, kindName)
+			}
+		}
+
+		for _, kindName := range slices.Sorted(maps.Keys(crd.Options)) {
+			if _, ok := kinds[kindName]; !ok {
+				invalid("options are defined for kind %v which is not registered", kindName)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// listItemType returns the type of the items of the list type t, or nil where it cannot be determined
+func listItemType(t reflect.Type) reflect.Type {
+	items, ok := t.Elem().FieldByName("Items")
+
+	if !ok || items.Type.Kind() != reflect.Slice {
+		return nil
+	}
+
+	if items.Type.Elem().Kind() == reflect.Pointer {
+		return items.Type.Elem()
+	}
+
+	return reflect.PointerTo(items.Type.Elem())
+}
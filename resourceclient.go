@@ -0,0 +1,159 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type (
+	// ResourceClient is a Client for resources of type T that does not require the list type of T to be specified. It is created with For.
+	//
+	// It supports all of the operations of a Client, except that List and ListByIndex return the matching resources as a []T
+	ResourceClient[T client.Object] struct {
+		*Client[T, client.ObjectList]
+	}
+)
+
+// For returns a ResourceClient that can be used to perform various IO operations against resources of type T on a k8s cluster.
+//
+// The list type of T is resolved from the kinds registered with the kapi.Cluster: the `<Kind>List` kind of built-in types and CRDs.
+// Where T is registered without a `<Kind>List` kind, resources are listed as unstructured and converted to T.
+//
+// Caching and ClientOptions behave as described for ClientFor.
+func For[T client.Object](ctx context.Context, cluster *Cluster, cache bool, opts ...ClientOption) *ResourceClient[T] {
+	var zeroOfT T
+
+	newResource := func() T { return reflect.New(reflect.TypeOf(zeroOfT).Elem()).Interface().(T) }
+	newResourceList, resourceListType, err := listFor[T](cluster.manager.GetScheme(), newResource())
+
+	c := newClient(ctx, cluster, cache, newResource, newResourceList, fmt.Sprintf("%T", zeroOfT), resourceListType, opts)
+	c.listErr = err
+
+	return &ResourceClient[T]{Client: c}
+}
+
+// List returns all occurences of the resource type associated with the client.
+//
+// If WithoutCache is passed, the resources are read directly from the cluster, even if the client is cached
+func (c *ResourceClient[T]) List(ctx context.Context, opts ...Option) ([]T, error) {
+	resourceList, err := c.Client.List(ctx, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items[T](resourceList)
+}
+
+// ListByIndex returns all occurences of the resource type associated with the client that are indexed under the specified
+// value by the named index. Indexes are registered with AddIndex.
+//
+// As indexes are maintained in the cache, ListByIndex is only supported by cached clients
+func (c *ResourceClient[T]) ListByIndex(ctx context.Context, name, value string) ([]T, error) {
+	resourceList, err := c.Client.ListByIndex(ctx, name, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return items[T](resourceList)
+}
+
+// As returns a copy of the ResourceClient that performs all operations as the specified user and groups, using k8s impersonation.
+//
+// See Client.As for details
+func (c *ResourceClient[T]) As(user string, groups ...string) (*ResourceClient[T], error) {
+	impersonated, err := c.Client.As(user, groups...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResourceClient[T]{Client: impersonated}, nil
+}
+
+// listFor returns a func that creates the list type of T, as resolved from the scheme, along with the name of the list type. Where T is
+// registered without a list kind, the func creates an unstructured list of the list kind of T.
+//
+// Where T is not registered, an error is returned along with a func that returns nil; this allows clients that never list, such as those
+// used by reconcilers, to be created for any T. Operations that require the list type return the error
+func listFor[T client.Object](scheme *runtime.Scheme, resource T) (func() client.ObjectList, string, error) {
+	unresolved := func() client.ObjectList { return nil }
+
+	gvk, err := apiutil.GVKForObject(resource, scheme)
+
+	if err != nil {
+		return unresolved, "", fmt.Errorf("unable to list %T as it is not registered with the kapi.cluster. %v", resource, err)
+	}
+
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	obj, err := scheme.New(listGVK)
+
+	if runtime.IsNotRegisteredError(err) {
+		newResourceList := func() client.ObjectList {
+			resourceList := &unstructured.UnstructuredList{}
+			resourceList.SetGroupVersionKind(listGVK)
+
+			return resourceList
+		}
+
+		return newResourceList, fmt.Sprintf("%T", &unstructured.UnstructuredList{}), nil
+	}
+
+	if err != nil {
+		return unresolved, "", fmt.Errorf("unable to list %T as its list kind of %v could not be created. %v", resource, listGVK.Kind, err)
+	}
+
+	if _, ok := obj.(client.ObjectList); !ok {
+		return unresolved, "", fmt.Errorf("unable to list %T as its registered list kind of %T is not a list", resource, obj)
+	}
+
+	newResourceList := func() client.ObjectList {
+		obj, _ := scheme.New(listGVK)
+		return obj.(client.ObjectList)
+	}
+
+	return newResourceList, fmt.Sprintf("%T", obj), nil
+}
+
+func items[T client.Object](resourceList client.ObjectList) ([]T, error) {
+	objs, err := meta.ExtractList(resourceList)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract items from resource list. %v", err)
+	}
+
+	resources := make([]T, 0, len(objs))
+
+	for _, obj := range objs {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			var zeroOfT T
+
+			resource := reflect.New(reflect.TypeOf(zeroOfT).Elem()).Interface().(T)
+
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), resource); err != nil {
+				return nil, fmt.Errorf("unable to convert unstructured resource list item to %T. %v", resource, err)
+			}
+
+			resources = append(resources, resource)
+			continue
+		}
+
+		resource, ok := obj.(T)
+
+		if !ok {
+			return nil, fmt.Errorf("resource list item of type %T is not of type %T", obj, resource)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
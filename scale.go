@@ -0,0 +1,88 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetScale returns the scale subresource of the specified resource; its desired and observed number of replicas.
+//
+// The resource type must support the scale subresource; for a kapi CustomResource this is enabled with KindOptions.Scale
+func (c *Client[TItem, TList]) GetScale(ctx context.Context, namespace, name string) (*autoscalingv1.Scale, error) {
+	scale := &autoscalingv1.Scale{}
+
+	return scale, c.intercept(ctx, ActionGet, namespace, name, scale, func(ctx context.Context) error {
+		clt, err := c.getClient(true)
+
+		if err != nil {
+			return err
+		}
+
+		resource := c.newResource()
+		resource.SetNamespace(namespace)
+		resource.SetName(name)
+
+		if err := clt.SubResource(string(SubresourceScale)).Get(ctx, resource, scale); err != nil {
+			return fmt.Errorf("unable to get subresource %v. %w", SubresourceScale, err)
+		}
+
+		return nil
+	})
+}
+
+// UpdateScale sets the desired number of replicas of the resource identified by the name and namespace of the specified scale subresource.
+// The scale is updated to reflect the resulting state of the subresource.
+//
+// Passing a scale with a resourceVersion, as returned by GetScale, limits the update to only proceed if the resource is unchanged.
+// If DryRun is passed, the update is processed by the cluster but not persisted
+func (c *Client[TItem, TList]) UpdateScale(ctx context.Context, scale *autoscalingv1.Scale, opts ...Option) error {
+	return c.intercept(ctx, ActionUpdate, scale.Namespace, scale.Name, scale, func(ctx context.Context) error {
+		clt, err := c.getClient(false)
+
+		if err != nil {
+			return err
+		}
+
+		o := newWriteOptions(ctx, opts)
+
+		resource := c.newResource()
+		resource.SetNamespace(scale.Namespace)
+		resource.SetName(scale.Name)
+
+		var original runtime.Object
+
+		if o.dryRun {
+			// the original scale is read directly, rather than with GetScale, so that the update is not also intercepted as a get
+			current := &autoscalingv1.Scale{}
+
+			if err := c.uncachedClient.SubResource(string(SubresourceScale)).Get(ctx, resource, current); err == nil {
+				original = current
+			}
+		}
+
+		updateOpts := []client.SubResourceUpdateOption{client.WithSubResourceBody(scale)}
+
+		if o.dryRun {
+			updateOpts = append(updateOpts, client.DryRunAll)
+		}
+
+		if err := clt.SubResource(string(SubresourceScale)).Update(ctx, resource, updateOpts...); err != nil {
+			return fmt.Errorf("unable to update subresource %v. %w", SubresourceScale, err)
+		}
+
+		if o.dryRun {
+			c.logDryRun(ctx, ActionUpdate, scale.Namespace, scale.Name, original, scale)
+			return nil
+		}
+
+		// the resource version of the scale subresource is that of the resource itself, so allows read-your-writes consistency for the resource
+		resource.SetResourceVersion(scale.ResourceVersion)
+		c.recordWrite(resource, false)
+
+		return nil
+	})
+}
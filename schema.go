@@ -0,0 +1,200 @@
+package kapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var (
+	fieldUndefinedType = reflect.TypeOf(FieldUndefined(nil))
+	objectMetaType     = reflect.TypeOf(metav1.ObjectMeta{})
+	timeType           = reflect.TypeOf(metav1.Time{})
+	durationType       = reflect.TypeOf(metav1.Duration{})
+	quantityType       = reflect.TypeOf(resource.Quantity{})
+	intOrStringType    = reflect.TypeOf(intstr.IntOrString{})
+	rawExtensionType   = reflect.TypeOf(runtime.RawExtension{})
+)
+
+// schemaFor generates an OpenAPI v3 schema for the specified type, based on its fields and their json tags.
+//
+// Fields without the omitempty json option are required. Fields of type FieldUndefined and those with a json name of "-" are omitted.
+// The defaults and validation rules declared in the kapi tags of fields, and any CEL rules, are included in their schemas
+func schemaFor(t reflect.Type) (apiextensionsv1.JSONSchemaProps, error) {
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) (apiextensionsv1.JSONSchemaProps, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case objectMetaType:
+		return apiextensionsv1.JSONSchemaProps{Type: "object"}, nil
+	case timeType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "date-time"}, nil
+	case durationType:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}, nil
+	case quantityType, intOrStringType:
+		return apiextensionsv1.JSONSchemaProps{XIntOrString: true, AnyOf: []apiextensionsv1.JSONSchemaProps{{Type: "integer"}, {Type: "string"}}}, nil
+	case rawExtensionType:
+		return apiextensionsv1.JSONSchemaProps{Type: "object", XPreserveUnknownFields: ptrTo(true)}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}, nil
+	case reflect.Bool:
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}, nil
+	case reflect.Interface:
+		return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptrTo(true)}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return apiextensionsv1.JSONSchemaProps{Type: "string", Format: "byte"}, nil
+		}
+
+		items, err := schemaForType(t.Elem(), visiting)
+
+		if err != nil {
+			return apiextensionsv1.JSONSchemaProps{}, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{Type: "array", Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items}}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unable to generate schema for map %v with non-string keys", t)
+		}
+
+		values, err := schemaForType(t.Elem(), visiting)
+
+		if err != nil {
+			return apiextensionsv1.JSONSchemaProps{}, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{Type: "object", AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &values}}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unable to generate schema for recursive type %v", t)
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := apiextensionsv1.JSONSchemaProps{Type: "object", Properties: map[string]apiextensionsv1.JSONSchemaProps{}, XValidations: celTypeRules(t)}
+
+		if err := addFieldSchemas(&schema, t, visiting); err != nil {
+			return apiextensionsv1.JSONSchemaProps{}, err
+		}
+
+		return schema, nil
+	default:
+		return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unable to generate schema for type %v of kind %v", t, t.Kind())
+	}
+}
+
+// addFieldSchemas adds the schema of each field of the struct type t to the properties of schema. The fields of embedded structs without
+// a json name are added as if they were fields of t
+func addFieldSchemas(schema *apiextensionsv1.JSONSchemaProps, t reflect.Type, visiting map[reflect.Type]bool) error {
+	for field := range structFields(t) {
+		name, omitEmpty, inline := jsonName(field)
+
+		if name == "-" || field.Type == fieldUndefinedType {
+			continue
+		}
+
+		if inline {
+			embedded := field.Type
+
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+
+			if err := addFieldSchemas(schema, embedded, visiting); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		fieldSchema, err := schemaForType(field.Type, visiting)
+
+		if err != nil {
+			return fmt.Errorf("unable to generate schema for field %v. %w", name, err)
+		}
+
+		required, err := tagSchema(&fieldSchema, field.Type, field.Tag.Get("kapi"))
+
+		if err != nil {
+			return fmt.Errorf("invalid kapi tag on field %v. %w", name, err)
+		}
+
+		fieldSchema.XValidations = append(fieldSchema.XValidations, celFieldRules(field)...)
+
+		schema.Properties[name] = fieldSchema
+
+		if required || (!omitEmpty && field.Type.Kind() != reflect.Pointer) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return nil
+}
+
+// structFields returns the exported fields of the struct type t
+func structFields(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName returns the name a struct field is serialised with, whether it is omitted when empty and whether its fields are inlined
+func jsonName(field reflect.StructField) (name string, omitEmpty, inline bool) {
+	tag := field.Tag.Get("json")
+	name, opts, _ := strings.Cut(tag, ",")
+
+	for opt := range strings.SplitSeq(opts, ",") {
+		switch opt {
+		case "omitempty", "omitzero":
+			omitEmpty = true
+		case "inline":
+			inline = true
+		}
+	}
+
+	if name == "" {
+		if field.Anonymous {
+			return "", omitEmpty, true
+		}
+
+		name = field.Name
+	}
+
+	return name, omitEmpty, inline
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
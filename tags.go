@@ -0,0 +1,510 @@
+package kapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type (
+	// tagRule defines the defaulting and validation rules, and the printer column, declared by the `kapi` tag of a field; for example
+	//
+	//	Replicas *int32 `json:"replicas,omitempty" kapi:"default=1,min=1,max=10,column=Replicas"`
+	//	Tier     string `json:"tier" kapi:"required,enum=bronze|silver|gold,column=Tier,wide"`
+	//	Name     string `json:"name" kapi:"pattern=^[a-z]+$"`
+	//	Region   string `json:"region" kapi:"immutable"`
+	//
+	// Defaults may only be declared on pointer, slice and map fields, as the zero value of other fields cannot be distinguished from a value
+	// explicitly set to zero, such as 0, false or "".
+	//
+	// Immutable fields are enforced with a CEL transition rule, so are validated alongside other CEL rules rather than with the tag rules.
+	// As a pattern may contain commas, it must be the last option in a tag
+	tagRule struct {
+		defaultValue *string
+		min, max     *float64
+		enum         []string
+		pattern      *regexp.Regexp
+		required     bool
+		immutable    bool
+		column       string
+		wide         bool
+	}
+	// fieldRule associates a tagRule with a field of a struct type
+	fieldRule struct {
+		index     int
+		name      string
+		omitEmpty bool
+		inline    bool
+		rule      tagRule
+	}
+	fieldRulesResult struct {
+		fieldRules []fieldRule
+		err        error
+	}
+)
+
+var (
+	fieldRulesCache sync.Map // map[reflect.Type]fieldRulesResult
+)
+
+// parseTagRule parses the value of a `kapi` tag
+func parseTagRule(tag string) (tagRule, error) {
+	rule := tagRule{}
+
+	for tag != "" {
+		var opt string
+
+		if strings.HasPrefix(tag, "pattern=") {
+			opt, tag = tag, ""
+		} else {
+			opt, tag, _ = strings.Cut(tag, ",")
+		}
+
+		key, value, _ := strings.Cut(opt, "=")
+
+		switch key {
+		case "default":
+			rule.defaultValue = &value
+		case "min", "max":
+			limit, err := strconv.ParseFloat(value, 64)
+
+			if err != nil {
+				return tagRule{}, fmt.Errorf("invalid %v value %q. %v", key, value, err)
+			}
+
+			if key == "min" {
+				rule.min = &limit
+			} else {
+				rule.max = &limit
+			}
+		case "enum":
+			rule.enum = strings.Split(value, "|")
+		case "pattern":
+			pattern, err := regexp.Compile(value)
+
+			if err != nil {
+				return tagRule{}, fmt.Errorf("invalid pattern %q. %v", value, err)
+			}
+
+			rule.pattern = pattern
+		case "required":
+			rule.required = true
+		case "immutable":
+			rule.immutable = true
+		case "column":
+			if value == "" {
+				return tagRule{}, fmt.Errorf("a column requires a name")
+			}
+
+			rule.column = value
+		case "wide":
+			rule.wide = true
+		case "":
+		default:
+			return tagRule{}, fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	if rule.wide && rule.column == "" {
+		return tagRule{}, fmt.Errorf("wide requires a column")
+	}
+
+	return rule, nil
+}
+
+// validateFor returns an error if the rule cannot be applied to a field of type t
+func (r tagRule) validateFor(t reflect.Type) error {
+	if r.defaultValue != nil && !isNillable(t.Kind()) {
+		return fmt.Errorf("a default requires a pointer, slice or map field, as an explicit zero value of %v cannot be distinguished from an unset one", t)
+	}
+
+	return nil
+}
+
+// fieldRulesFor returns the fieldRules of the fields of the struct type t, parsing and caching them on first use
+func fieldRulesFor(t reflect.Type) ([]fieldRule, error) {
+	if result, ok := fieldRulesCache.Load(t); ok {
+		return result.(fieldRulesResult).fieldRules, result.(fieldRulesResult).err
+	}
+
+	result := fieldRulesResult{}
+
+	for field := range structFields(t) {
+		name, omitEmpty, inline := jsonName(field)
+
+		if name == "-" || field.Type == fieldUndefinedType {
+			continue
+		}
+
+		rule, err := parseTagRule(field.Tag.Get("kapi"))
+
+		if err == nil {
+			err = rule.validateFor(field.Type)
+		}
+
+		if err != nil {
+			result.err = fmt.Errorf("invalid kapi tag on field %v of %v. %v", field.Name, t, err)
+			break
+		}
+
+		result.fieldRules = append(result.fieldRules, fieldRule{index: field.Index[0], name: name, omitEmpty: omitEmpty, inline: inline, rule: rule})
+	}
+
+	fieldRulesCache.Store(t, result)
+
+	return result.fieldRules, result.err
+}
+
+// validateTags returns an error if the kapi tags of any field of the passed type, or of the types it contains, are invalid
+func validateTags(t reflect.Type) error {
+	return validateTagsOfType(t, map[reflect.Type]bool{})
+}
+
+func validateTagsOfType(t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || visited[t] || t == objectMetaType {
+		return nil
+	}
+
+	visited[t] = true
+
+	fieldRules, err := fieldRulesFor(t)
+
+	if err != nil {
+		return err
+	}
+
+	for fr := range slices.Values(fieldRules) {
+		if err := validateTagsOfType(t.Field(fr.index).Type, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// declaredTagRules returns whether the kapi tags of the fields of the type, and of the structs it contains, declare any defaults and any validation rules
+func declaredTagRules(t reflect.Type) (defaults, validation bool) {
+	visited := map[reflect.Type]bool{}
+
+	var walk func(t reflect.Type)
+
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct || visited[t] || t == objectMetaType {
+			return
+		}
+
+		visited[t] = true
+
+		fieldRules, _ := fieldRulesFor(t)
+
+		for fr := range slices.Values(fieldRules) {
+			rule := fr.rule
+			defaults = defaults || rule.defaultValue != nil
+			validation = validation || rule.required || rule.immutable || rule.min != nil || rule.max != nil || len(rule.enum) > 0 || rule.pattern != nil
+			walk(t.Field(fr.index).Type)
+		}
+	}
+
+	walk(t)
+
+	return defaults, validation
+}
+
+// applyTagDefaults sets each field of the passed resource, and of the structs it contains, that is nil and has a default declared in its kapi tag
+func applyTagDefaults(resource any) error {
+	return walkTagRules(reflect.ValueOf(resource), nil, func(v reflect.Value, path *field.Path, fr fieldRule) error {
+		if fr.rule.defaultValue == nil || !isNillable(v.Kind()) || !v.IsNil() {
+			return nil
+		}
+
+		defaultValue, err := parseValue(v.Type(), *fr.rule.defaultValue)
+
+		if err != nil {
+			return fmt.Errorf("invalid default for field %v. %v", path, err)
+		}
+
+		v.Set(defaultValue)
+
+		return nil
+	})
+}
+
+// validateTagRules returns the violations of the rules declared in the kapi tags of the fields of the passed resource, and of the structs it contains
+func validateTagRules(resource any) field.ErrorList {
+	errs := field.ErrorList{}
+
+	walkTagRules(reflect.ValueOf(resource), nil, func(v reflect.Value, path *field.Path, fr fieldRule) error {
+		missing := v.IsZero()
+
+		if fr.rule.required && missing {
+			errs = append(errs, field.Required(path, "a value is required"))
+			return nil
+		}
+
+		if missing && (fr.omitEmpty || isNillable(v.Kind())) {
+			return nil
+		}
+
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+
+		if size, ok := sizeOf(v); ok {
+			if fr.rule.min != nil && size < *fr.rule.min {
+				errs = append(errs, field.Invalid(path, v.Interface(), fmt.Sprintf("must be no less than %v", *fr.rule.min)))
+			}
+
+			if fr.rule.max != nil && size > *fr.rule.max {
+				errs = append(errs, field.Invalid(path, v.Interface(), fmt.Sprintf("must be no greater than %v", *fr.rule.max)))
+			}
+		}
+
+		if len(fr.rule.enum) > 0 && !slices.Contains(fr.rule.enum, fmt.Sprint(v.Interface())) {
+			errs = append(errs, field.NotSupported(path, v.Interface(), fr.rule.enum))
+		}
+
+		if fr.rule.pattern != nil && v.Kind() == reflect.String && !fr.rule.pattern.MatchString(v.String()) {
+			errs = append(errs, field.Invalid(path, v.Interface(), fmt.Sprintf("must match the pattern %v", fr.rule.pattern)))
+		}
+
+		return nil
+	})
+
+	return errs
+}
+
+// walkTagRules invokes fn for each field of v, and of the structs it contains, along with the field's path and rules.
+// fn is invoked before a field's own fields are walked, so fields created by defaults are themselves walked
+func walkTagRules(v reflect.Value, path *field.Path, fn func(v reflect.Value, path *field.Path, fr fieldRule) error) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+
+		return walkTagRules(v.Elem(), path, fn)
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := walkTagRules(v.Index(i), path.Index(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			if iter.Value().Kind() != reflect.Pointer {
+				continue // map values are not addressable, so only those referenced by pointers can be walked
+			}
+
+			if err := walkTagRules(iter.Value(), path.Key(fmt.Sprint(iter.Key().Interface())), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == objectMetaType {
+			return nil
+		}
+
+		fieldRules, err := fieldRulesFor(v.Type())
+
+		if err != nil {
+			return err
+		}
+
+		for fr := range slices.Values(fieldRules) {
+			fieldValue := v.Field(fr.index)
+			fieldPath := path
+
+			if !fr.inline {
+				fieldPath = path.Child(fr.name)
+
+				if err := fn(fieldValue, fieldPath, fr); err != nil {
+					return err
+				}
+			}
+
+			if err := walkTagRules(fieldValue, fieldPath, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// tagSchema applies the rules declared in the kapi tag of a field to the field's schema.
+// It returns true if the field is required by the rules
+func tagSchema(schema *apiextensionsv1.JSONSchemaProps, t reflect.Type, tag string) (bool, error) {
+	rule, err := parseTagRule(tag)
+
+	if err != nil {
+		return false, err
+	}
+
+	if err := rule.validateFor(t); err != nil {
+		return false, err
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if rule.defaultValue != nil {
+		raw, err := jsonValue(t, *rule.defaultValue)
+
+		if err != nil {
+			return false, fmt.Errorf("invalid default. %v", err)
+		}
+
+		schema.Default = &apiextensionsv1.JSON{Raw: raw}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		schema.MinLength, schema.MaxLength = int64Of(rule.min), int64Of(rule.max)
+	case reflect.Slice, reflect.Array:
+		schema.MinItems, schema.MaxItems = int64Of(rule.min), int64Of(rule.max)
+	case reflect.Map:
+		schema.MinProperties, schema.MaxProperties = int64Of(rule.min), int64Of(rule.max)
+	default:
+		schema.Minimum, schema.Maximum = rule.min, rule.max
+	}
+
+	for value := range slices.Values(rule.enum) {
+		raw, err := jsonValue(t, value)
+
+		if err != nil {
+			return false, fmt.Errorf("invalid enum value. %v", err)
+		}
+
+		schema.Enum = append(schema.Enum, apiextensionsv1.JSON{Raw: raw})
+	}
+
+	if rule.pattern != nil {
+		schema.Pattern = rule.pattern.String()
+	}
+
+	if rule.immutable {
+		schema.XValidations = append(schema.XValidations, apiextensionsv1.ValidationRule{Rule: "self == oldSelf", Message: "field is immutable"})
+	}
+
+	return rule.required, nil
+}
+
+// parseValue parses the string representation of a value of type t, as used in a kapi tag. Scalar values are parsed as-is, other
+// values are parsed as json
+func parseValue(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	target := v
+
+	if t.Kind() == reflect.Pointer {
+		target = reflect.New(t.Elem())
+		v.Set(target)
+		target = target.Elem()
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, target.Type().Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		target.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, target.Type().Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		target.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, target.Type().Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		target.SetFloat(f)
+	default:
+		if err := json.Unmarshal([]byte(s), target.Addr().Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return v, nil
+}
+
+// jsonValue returns the json representation of the string representation of a value of type t, as used in a kapi tag
+func jsonValue(t reflect.Type, s string) ([]byte, error) {
+	v, err := parseValue(t, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v.Interface())
+}
+
+// sizeOf returns the value of a number, the length of a string or the number of items in a slice, array or map
+func sizeOf(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+func isNillable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	default:
+		return false
+	}
+}
+
+func int64Of(f *float64) *int64 {
+	if f == nil {
+		return nil
+	}
+
+	return ptrTo(int64(*f))
+}
@@ -0,0 +1,178 @@
+package kapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/comradequinn/kapi/internal/certs"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type (
+	// certRotator periodically rotates the generated webhook certificates and refreshes those written by other replicas.
+	// It runs on every replica, regardless of leader election, as each serves the webhooks
+	certRotator struct {
+		cluster *Cluster
+		cfg     certs.Config
+		bundle  certs.Bundle
+	}
+)
+
+const (
+	// defaultCertValidity is the validity of generated webhook certificates where WebhookConfig.CertValidity is unset
+	defaultCertValidity = 365 * 24 * time.Hour
+	// certRefreshInterval is the maximum interval between reads of the Secret storing the generated webhook certificates
+	certRefreshInterval = 10 * time.Minute
+)
+
+var _ manager.LeaderElectionRunnable = &certRotator{} // implementation guard
+
+// certsConfig returns the configuration of the certificates generated for the webhook Service
+func (w WebhookConfig) certsConfig() certs.Config {
+	cfg := certs.Config{
+		ServiceName:      w.ServiceName,
+		ServiceNamespace: w.ServiceNamespace,
+		SecretName:       w.CertSecret,
+		Validity:         w.CertValidity,
+	}
+
+	if cfg.SecretName == "" {
+		cfg.SecretName = w.ServiceName + "-webhook-tls"
+	}
+
+	if cfg.Validity == 0 {
+		cfg.Validity = defaultCertValidity
+	}
+
+	return cfg
+}
+
+// certDir returns the directory from which the webhook server loads its certificates; the configured directory or, where certificates are
+// generated and none is configured, a temporary directory
+func (cfg ClusterConfig) certDir() (string, error) {
+	if !cfg.Webhooks.GenerateCerts || cfg.TLS != "" {
+		return cfg.TLS, nil
+	}
+
+	dir, err := os.MkdirTemp("", "kapi-webhook-certs-")
+
+	if err != nil {
+		return "", fmt.Errorf("unable to create directory for generated webhook certificates. %v", err)
+	}
+
+	return dir, nil
+}
+
+// provisionCerts generates, or loads where another replica has generated them, the webhook certificates and writes them to the certificate
+// directory of the webhook server. A certRotator is added to the manager to rotate them before they expire
+func (cluster *Cluster) provisionCerts(ctx context.Context) error {
+	if !cluster.webhooks.GenerateCerts {
+		return nil
+	}
+
+	defer obs.MetricTimerFunc(ctx, "kapi_provision_certs")()
+
+	cfg := cluster.webhooks.certsConfig()
+	bundle, err := certs.Ensure(ctx, cluster.uncachedClient, cfg)
+
+	if err != nil {
+		return fmt.Errorf("unable to ensure webhook certificates. %v", err)
+	}
+
+	if _, err := certs.Write(cluster.certDir, bundle); err != nil {
+		return fmt.Errorf("unable to write webhook certificates. %v", err)
+	}
+
+	obs.LogFunc(ctx, 3, "provisioned webhook certificates", "secret", cfg.ServiceNamespace+"/"+cfg.SecretName, "dir", cluster.certDir, "renew_at", bundle.RenewAt().String())
+
+	cluster.caBundle = bundle.CABundle
+
+	if err := cluster.manager.Add(&certRotator{cluster: cluster, cfg: cfg, bundle: bundle}); err != nil {
+		return fmt.Errorf("unable to add webhook certificate rotator. %v", err)
+	}
+
+	return nil
+}
+
+// injectCABundle sets the CA bundle of the webhook configurations and CustomResourceDefinition conversion webhooks that reference the webhook Service
+func (cluster *Cluster) injectCABundle(ctx context.Context) error {
+	if !cluster.webhooks.GenerateCerts {
+		return nil
+	}
+
+	defer obs.MetricTimerFunc(ctx, "kapi_inject_ca_bundle")()
+
+	patched, err := certs.InjectCABundle(ctx, cluster.uncachedClient, cluster.webhooks.certsConfig(), cluster.caBundle)
+
+	if err != nil {
+		return err
+	}
+
+	obs.LogFunc(ctx, 3, "injected ca bundle", "patched", patched)
+
+	return nil
+}
+
+func (r *certRotator) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *certRotator) Start(ctx context.Context) error {
+	for {
+		interval := min(certRefreshInterval, max(time.Until(r.bundle.RenewAt()), time.Second))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+
+		if err := r.refresh(ctx); err != nil {
+			obs.LogFunc(ctx, 0, "unable to refresh webhook certificates", "error", err.Error())
+		}
+	}
+}
+
+// refresh rotates the certificates where they are due for renewal, or loads those rotated by another replica, and applies any change.
+//
+// The CA bundle, which includes both the new and previous CA, is injected before the new serving certificate is written, so that the API server
+// can verify the certificate served by this replica throughout the rotation. Where the injection fails, the previous certificate continues to be
+// served and the rotation is retried
+func (r *certRotator) refresh(ctx context.Context) error {
+	defer obs.MetricTimerFunc(ctx, "kapi_refresh_certs")()
+
+	bundle, err := certs.Ensure(ctx, r.cluster.uncachedClient, r.cfg)
+
+	if err != nil {
+		return fmt.Errorf("unable to ensure webhook certificates. %v", err)
+	}
+
+	r.cluster.caBundleMu.Lock()
+	defer r.cluster.caBundleMu.Unlock()
+
+	if previous := r.cluster.caBundle; !bytes.Equal(previous, bundle.CABundle) {
+		r.cluster.caBundle = bundle.CABundle
+
+		if err := r.cluster.injectCABundle(ctx); err != nil {
+			r.cluster.caBundle = previous
+			return fmt.Errorf("unable to inject rotated ca bundle. %v", err)
+		}
+	}
+
+	changed, err := certs.Write(r.cluster.certDir, bundle)
+
+	if err != nil {
+		return fmt.Errorf("unable to write webhook certificates. %v", err)
+	}
+
+	r.bundle = bundle
+
+	if changed {
+		obs.LogFunc(ctx, 2, "webhook certificates rotated", "renew_at", bundle.RenewAt().String())
+	}
+
+	return nil
+}
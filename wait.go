@@ -0,0 +1,233 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaitFor blocks until the specified resource exists and satisfies the passed condition, or until the ctx is done.
+//
+// The resource is observed using a watch rather than by polling, so the condition is evaluated each time the resource changes.
+// The state of the resource that satisfied the condition is returned.
+//
+// To limit the time spent waiting, pass a ctx with a deadline.
+func (c *Client[TItem, TList]) WaitFor(ctx context.Context, namespace, name string, condition func(TItem) bool) (TItem, error) {
+	defer c.observe(ctx, "wait_for", nil)()
+
+	return c.waitFor(ctx, namespace, name, func(resource TItem, exists bool) bool {
+		return exists && condition(resource)
+	})
+}
+
+// WaitForDeletion blocks until the specified resource no longer exists, or until the ctx is done.
+//
+// If the resource does not exist when WaitForDeletion is called, it returns immediately.
+//
+// To limit the time spent waiting, pass a ctx with a deadline.
+func (c *Client[TItem, TList]) WaitForDeletion(ctx context.Context, namespace, name string) error {
+	defer c.observe(ctx, "wait_for_deletion", nil)()
+
+	_, err := c.waitFor(ctx, namespace, name, func(_ TItem, exists bool) bool {
+		return !exists
+	})
+
+	return err
+}
+
+// WaitForByName blocks until the specified cluster-scoped resource exists and satisfies the passed condition, or until the ctx is done.
+//
+// It behaves as WaitFor
+func (c *Client[TItem, TList]) WaitForByName(ctx context.Context, name string, condition func(TItem) bool) (TItem, error) {
+	return c.WaitFor(ctx, "", name, condition)
+}
+
+// WaitForDeletionByName blocks until the specified cluster-scoped resource no longer exists, or until the ctx is done.
+//
+// It behaves as WaitForDeletion
+func (c *Client[TItem, TList]) WaitForDeletionByName(ctx context.Context, name string) error {
+	return c.WaitForDeletion(ctx, "", name)
+}
+
+// waitFor lists the named resource and then watches it from the returned resource version until done returns true.
+// If the watch is closed by the server, the resource is re-listed and a new watch is established
+func (c *Client[TItem, TList]) waitFor(ctx context.Context, namespace, name string, done func(resource TItem, exists bool) bool) (TItem, error) {
+	var zeroOfTItem TItem
+
+	if !c.cluster.connected {
+		panic("kapi.client used before kapi.cluster.connect called")
+	}
+
+	if c.listErr != nil {
+		return zeroOfTItem, c.listErr
+	}
+
+	listOpts := []client.ListOption{
+		client.InNamespace(namespace),
+		client.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector("metadata.name", name)},
+	}
+
+	for {
+		resourceList := c.newResourceList()
+
+		if err := c.uncachedClient.List(ctx, resourceList, listOpts...); err != nil {
+			return zeroOfTItem, fmt.Errorf("unable to list resource %v/%v. %w", namespace, name, err)
+		}
+
+		items, err := meta.ExtractList(resourceList)
+
+		if err != nil {
+			return zeroOfTItem, fmt.Errorf("unable to extract items from resource list. %v", err)
+		}
+
+		if len(items) == 0 {
+			if done(zeroOfTItem, false) {
+				return zeroOfTItem, nil
+			}
+		} else if resource, ok := items[0].(TItem); ok && done(resource, true) {
+			return resource, nil
+		}
+
+		watcher, err := c.uncachedClient.Watch(ctx, resourceList, append(listOpts, &client.ListOptions{
+			Raw: &metav1.ListOptions{ResourceVersion: resourceList.GetResourceVersion()},
+		})...)
+
+		if err != nil {
+			return zeroOfTItem, fmt.Errorf("unable to watch resource %v/%v. %w", namespace, name, err)
+		}
+
+		resource, satisfied, err := watchUntil(ctx, watcher, done)
+
+		watcher.Stop()
+
+		if err != nil || satisfied {
+			return resource, err
+		}
+
+		obs.LogFunc(ctx, 3, "kapi.client watch closed before condition was satisfied. re-establishing", "resource_name", name, "resource_namespace", namespace)
+	}
+}
+
+// watchUntil consumes events from the watcher until done returns true, the watch is closed or the ctx is done
+func watchUntil[TItem client.Object](ctx context.Context, watcher watch.Interface, done func(resource TItem, exists bool) bool) (TItem, bool, error) {
+	var zeroOfTItem TItem
+
+	for {
+		select {
+		case <-ctx.Done():
+			return zeroOfTItem, false, fmt.Errorf("context done before condition was satisfied. %w", ctx.Err())
+		case evt, ok := <-watcher.ResultChan():
+			if !ok {
+				return zeroOfTItem, false, nil
+			}
+
+			switch evt.Type {
+			case watch.Added, watch.Modified:
+				if resource, ok := evt.Object.(TItem); ok && done(resource, true) {
+					return resource, true, nil
+				}
+			case watch.Deleted:
+				if done(zeroOfTItem, false) {
+					return zeroOfTItem, true, nil
+				}
+			case watch.Error:
+				return zeroOfTItem, false, nil
+			}
+		}
+	}
+}
+
+// HasCondition returns a condition func, for use with Client.WaitFor, that is satisfied when the resource reports a
+// `status.conditions` entry of the specified type with the specified status.
+//
+// It can be used with any resource that follows the metav1.Condition conventions, including CustomResources.
+func HasCondition[T client.Object](conditionType string, status metav1.ConditionStatus) func(T) bool {
+	return func(resource T) bool {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(resource)
+
+		if err != nil {
+			return false
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(u, "status", "conditions")
+
+		for _, c := range conditions {
+			condition, ok := c.(map[string]any)
+
+			if ok && condition["type"] == conditionType && condition["status"] == string(status) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// DeploymentRolledOut is a condition func, for use with Client.WaitFor, that is satisfied when the latest
+// generation of a Deployment has been observed and all of its replicas are updated and available
+func DeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false
+	}
+
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return false
+		}
+	}
+
+	replicas := int32(1)
+
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
+
+// PodReady is a condition func, for use with Client.WaitFor, that is satisfied when a Pod reports that it is ready
+func PodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// JobComplete is a condition func, for use with Client.WaitFor, that is satisfied when a Job reports that it has completed
+func JobComplete(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobComplete {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// CRDEstablished is a condition func, for use with Client.WaitFor, that is satisfied when a CustomResourceDefinition
+// has been established and its resources can be served
+func CRDEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextensionsv1.Established {
+			return c.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// WatchEventType defines the types of change to a resource that can be observed by Client.Watch
+	WatchEventType string
+	// WatchEvent describes a change to a resource observed by Client.Watch
+	WatchEvent[TItem client.Object] struct {
+		Type     WatchEventType
+		Resource TItem
+	}
+)
+
+const (
+	WatchEventTypeAdded    = WatchEventType(watch.Added)
+	WatchEventTypeModified = WatchEventType(watch.Modified)
+	WatchEventTypeDeleted  = WatchEventType(watch.Deleted)
+)
+
+// Watch returns a channel on which changes to resources of the type associated with the client, in the specified namespace, are delivered.
+// An empty namespace watches all namespaces.
+//
+// Resources that already exist when Watch is called are delivered as WatchEventTypeAdded events. If the watch is closed by the server
+// it is re-established from the last delivered resource version, so no events are missed or repeated. Where that version has expired,
+// as the server retains a limited history, the watch is re-established from the current state and every existing resource is delivered
+// again as a WatchEventTypeAdded event. The channel is closed when the ctx is done, or if the watch cannot be re-established.
+//
+// Watches are always served directly by the cluster, regardless of whether the client is cached.
+func (c *Client[TItem, TList]) Watch(ctx context.Context, namespace string) (<-chan WatchEvent[TItem], error) {
+	defer c.observe(ctx, "watch", nil)()
+
+	if !c.cluster.connected {
+		panic("kapi.client used before kapi.cluster.connect called")
+	}
+
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+
+	watcher, err := c.uncachedClient.Watch(ctx, c.newResourceList(), client.InNamespace(namespace))
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to watch resources. %w", err)
+	}
+
+	events := make(chan WatchEvent[TItem])
+
+	go func() {
+		defer close(events)
+
+		resourceVersion := ""
+
+		for {
+			for evt := range watcher.ResultChan() {
+				if evt.Type == watch.Error {
+					// where the resource version has expired the watch must be restarted from the current state, otherwise it resumes
+					if err := apierrors.FromObject(evt.Object); apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+						resourceVersion = ""
+					}
+					break
+				}
+
+				resource, ok := evt.Object.(TItem)
+
+				if !ok {
+					continue
+				}
+
+				resourceVersion = resource.GetResourceVersion()
+
+				select {
+				case events <- WatchEvent[TItem]{Type: WatchEventType(evt.Type), Resource: resource}:
+				case <-ctx.Done():
+				}
+			}
+
+			watcher.Stop()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			obs.LogFunc(ctx, 3, "kapi.client watch closed. re-establishing", "resource_type", c.resourceType, "resource_namespace", namespace)
+
+			watcher, err = c.uncachedClient.Watch(ctx, c.newResourceList(), client.InNamespace(namespace), &client.ListOptions{
+				Raw: &metav1.ListOptions{ResourceVersion: resourceVersion},
+			})
+
+			if (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) && resourceVersion != "" {
+				obs.LogFunc(ctx, 3, "kapi.client watch resource version expired. re-establishing from current state", "resource_type", c.resourceType, "resource_namespace", namespace)
+				resourceVersion = ""
+				watcher, err = c.uncachedClient.Watch(ctx, c.newResourceList(), client.InNamespace(namespace))
+			}
+
+			if err != nil {
+				obs.LogFunc(ctx, 0, "kapi.client unable to re-establish watch", "error", err, "resource_type", c.resourceType, "resource_namespace", namespace)
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
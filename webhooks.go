@@ -0,0 +1,343 @@
+package kapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+type (
+	// HookOptions defines how the API server invokes the webhooks of a Hook. They apply where ClusterConfig.Webhooks.InstallConfigurations is set
+	HookOptions struct {
+		// IgnoreFailures causes requests to be admitted where the webhook cannot be reached or fails to respond. By default, such requests are rejected
+		IgnoreFailures bool
+		// Timeout defines how long the API server waits for the webhook to respond; between 1 and 30 seconds. By default, this is 10 seconds
+		Timeout time.Duration
+		// NamespaceSelector limits the webhook to resources in namespaces with matching labels; for example "environment in (staging, production)"
+		NamespaceSelector string
+	}
+	// webhookInstaller installs the webhook configurations of the cluster once its webhook server is serving. Where they were installed
+	// before, under the Fail policy the API server would reject writes of the hooked kinds until the server had started
+	webhookInstaller struct {
+		cluster *Cluster
+	}
+	// hookRegistration defines the operations on a kind for which the webhooks of a Hook are invoked
+	hookRegistration struct {
+		gvk               schema.GroupVersionKind
+		validate, mutate  []admissionregistrationv1.OperationType
+		options           HookOptions
+		namespaceSelector *metav1.LabelSelector
+	}
+)
+
+const (
+	defaultHookTimeout = 10 * time.Second
+	// webhookServerPollInterval is the interval at which the webhook server is checked for whether it is serving
+	webhookServerPollInterval = 250 * time.Millisecond
+)
+
+var _ manager.LeaderElectionRunnable = &webhookInstaller{} // implementation guard
+
+// validate returns an error if the options are invalid, otherwise their namespace selector, if any
+func (o HookOptions) validate() (*metav1.LabelSelector, error) {
+	if o.Timeout != 0 && (o.Timeout < time.Second || o.Timeout > 30*time.Second) {
+		return nil, fmt.Errorf("timeout must be between 1 and 30 seconds, got %v", o.Timeout)
+	}
+
+	if o.NamespaceSelector == "" {
+		return nil, nil
+	}
+
+	selector, err := metav1.ParseToLabelSelector(o.NamespaceSelector)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector %q. %v", o.NamespaceSelector, err)
+	}
+
+	return selector, nil
+}
+
+// registration returns the hookRegistration of the Hook; validation is registered for each operation with a validator func, along with creates and
+// updates where the resource declares tag or CEL rules, and mutation is registered where there is a defaulter func or the resource declares tag defaults
+func (h *Hook[T]) registration(gvk schema.GroupVersionKind, t T) (hookRegistration, error) {
+	namespaceSelector, err := h.Options.validate()
+
+	if err != nil {
+		return hookRegistration{}, fmt.Errorf("invalid hook options. %v", err)
+	}
+
+	defaults, validation := declaredTagRules(reflect.TypeOf(t))
+	validation = validation || hasCELRules(h.schema)
+
+	r := hookRegistration{gvk: gvk, options: h.Options, namespaceSelector: namespaceSelector}
+
+	if h.ValidateCreateFunc != nil || validation {
+		r.validate = append(r.validate, admissionregistrationv1.Create)
+	}
+
+	if h.ValidateUpdateFunc != nil || validation {
+		r.validate = append(r.validate, admissionregistrationv1.Update)
+	}
+
+	if h.ValidateDeleteFunc != nil {
+		r.validate = append(r.validate, admissionregistrationv1.Delete)
+	}
+
+	if h.DefaulterFunc != nil || defaults {
+		r.mutate = []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}
+	}
+
+	return r, nil
+}
+
+// validate returns an error if the WebhookConfig lacks the Service required by the features it enables
+func (w WebhookConfig) validate() error {
+	if (w.GenerateCerts || w.InstallConfigurations) && (w.ServiceName == "" || w.ServiceNamespace == "") {
+		return fmt.Errorf("a service name and service namespace are required to generate webhook certificates or install webhook configurations")
+	}
+
+	if w.CertValidity < 0 {
+		return fmt.Errorf("webhook certificate validity must not be negative")
+	}
+
+	return nil
+}
+
+func (w WebhookConfig) port() int32 {
+	if w.ServicePort == 0 {
+		return 443
+	}
+
+	return w.ServicePort
+}
+
+// webhookConfigurations returns the Validating and Mutating webhook configurations for the hook registrations, named after the webhook Service.
+// resourceOf returns the resource name of a kind, as used in API paths; for example `pods` for `Pod`
+func (w WebhookConfig) webhookConfigurations(registrations []hookRegistration, caBundle []byte, resourceOf func(gvk schema.GroupVersionKind) (string, error)) (*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: w.ServiceName}}
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: w.ServiceName}}
+
+	for r := range slices.Values(registrations) {
+		resource, err := resourceOf(r.gvk)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to determine resource of %v. %v", r.gvk, err)
+		}
+
+		group := r.gvk.Group
+
+		if group == "" {
+			group = "core"
+		}
+
+		name := strings.ToLower(r.gvk.Kind) + "." + r.gvk.Version + "." + group
+		port := w.port()
+		failurePolicy := admissionregistrationv1.Fail
+
+		if r.options.IgnoreFailures {
+			failurePolicy = admissionregistrationv1.Ignore
+		}
+
+		timeout := r.options.Timeout
+
+		if timeout == 0 {
+			timeout = defaultHookTimeout
+		}
+
+		clientConfig := func(path string) admissionregistrationv1.WebhookClientConfig {
+			return admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: w.ServiceNamespace,
+					Name:      w.ServiceName,
+					Path:      ptrTo(path),
+					Port:      &port,
+				},
+				CABundle: caBundle,
+			}
+		}
+
+		rules := func(operations []admissionregistrationv1.OperationType) []admissionregistrationv1.RuleWithOperations {
+			return []admissionregistrationv1.RuleWithOperations{{
+				Operations: operations,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{r.gvk.Group},
+					APIVersions: []string{r.gvk.Version},
+					Resources:   []string{resource},
+					Scope:       ptrTo(admissionregistrationv1.AllScopes),
+				},
+			}}
+		}
+
+		if len(r.validate) > 0 {
+			validating.Webhooks = append(validating.Webhooks, admissionregistrationv1.ValidatingWebhook{
+				Name:                    name,
+				ClientConfig:            clientConfig(webhookPath("validate", r.gvk)),
+				Rules:                   rules(r.validate),
+				FailurePolicy:           ptrTo(failurePolicy),
+				NamespaceSelector:       r.namespaceSelector,
+				SideEffects:             ptrTo(admissionregistrationv1.SideEffectClassNone),
+				TimeoutSeconds:          ptrTo(int32(timeout / time.Second)),
+				AdmissionReviewVersions: []string{"v1"},
+			})
+		}
+
+		if len(r.mutate) > 0 {
+			mutating.Webhooks = append(mutating.Webhooks, admissionregistrationv1.MutatingWebhook{
+				Name:                    name,
+				ClientConfig:            clientConfig(webhookPath("mutate", r.gvk)),
+				Rules:                   rules(r.mutate),
+				FailurePolicy:           ptrTo(failurePolicy),
+				NamespaceSelector:       r.namespaceSelector,
+				SideEffects:             ptrTo(admissionregistrationv1.SideEffectClassNone),
+				TimeoutSeconds:          ptrTo(int32(timeout / time.Second)),
+				AdmissionReviewVersions: []string{"v1"},
+			})
+		}
+	}
+
+	return validating, mutating, nil
+}
+
+// webhookPath returns the path on which ctrl-runtime serves the validating or mutating webhook of a kind; for example `/validate-example-com-v1-widget`
+func webhookPath(prefix string, gvk schema.GroupVersionKind) string {
+	return "/" + prefix + "-" + strings.ReplaceAll(gvk.Group, ".", "-") + "-" + gvk.Version + "-" + strings.ToLower(gvk.Kind)
+}
+
+// addWebhookInstaller adds a webhookInstaller to the manager, where the webhook configurations are installed by kapi
+func (cluster *Cluster) addWebhookInstaller() error {
+	if !cluster.webhooks.InstallConfigurations {
+		return nil
+	}
+
+	return cluster.manager.Add(&webhookInstaller{cluster: cluster})
+}
+
+// NeedLeaderElection returns true so that only one replica installs the configurations
+func (i *webhookInstaller) NeedLeaderElection() bool {
+	return true
+}
+
+// Start waits for the webhook server to be serving, then installs the webhook configurations. A configuration without webhooks does not
+// depend on the server, so where there are no hooks, they are installed immediately
+func (i *webhookInstaller) Start(ctx context.Context) error {
+	if len(i.cluster.hooks) > 0 {
+		started := i.cluster.manager.GetWebhookServer().StartedChecker()
+
+		for started(nil) != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(webhookServerPollInterval):
+			}
+		}
+	}
+
+	i.cluster.caBundleMu.Lock()
+	defer i.cluster.caBundleMu.Unlock()
+
+	if err := i.cluster.installWebhooks(ctx); err != nil {
+		return fmt.Errorf("unable to install webhook configurations for kapi.cluster. %v", err)
+	}
+
+	return nil
+}
+
+// installWebhooks creates, or updates where they already exist, the Validating and Mutating webhook configurations of the hooks added to the cluster.
+// A configuration without any webhooks is deleted
+func (cluster *Cluster) installWebhooks(ctx context.Context) error {
+	if !cluster.webhooks.InstallConfigurations {
+		return nil
+	}
+
+	defer obs.MetricTimerFunc(ctx, "kapi_install_webhooks")()
+
+	validating, mutating, err := cluster.webhooks.webhookConfigurations(cluster.hooks, cluster.caBundle, func(gvk schema.GroupVersionKind) (string, error) {
+		mapping, err := cluster.manager.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+
+		if err != nil {
+			return "", err
+		}
+
+		return mapping.Resource.Resource, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := installWebhookConfiguration[*admissionregistrationv1.ValidatingWebhookConfiguration, *admissionregistrationv1.ValidatingWebhookConfigurationList](ctx, cluster, validating, len(validating.Webhooks) == 0); err != nil {
+		return err
+	}
+
+	return installWebhookConfiguration[*admissionregistrationv1.MutatingWebhookConfiguration, *admissionregistrationv1.MutatingWebhookConfigurationList](ctx, cluster, mutating, len(mutating.Webhooks) == 0)
+}
+
+func installWebhookConfiguration[T client.Object, TList client.ObjectList](ctx context.Context, cluster *Cluster, configuration T, empty bool) error {
+	obs.LogFunc(ctx, 3, "installing webhook configuration", "type", fmt.Sprintf("%T", configuration), "name", configuration.GetName(), "empty", empty)
+
+	klient := ClientFor[T, TList](ctx, cluster, false)
+	existing, err := klient.Get(ctx, "", configuration.GetName())
+
+	switch {
+	case IsNotFound(err) && empty:
+		return nil
+	case IsNotFound(err):
+		err = klient.Create(ctx, configuration)
+	case err == nil && empty:
+		err = klient.Delete(ctx, existing)
+	case err == nil:
+		// where kapi does not generate the certificates, the ca bundle is typically injected by an external controller, such as the cert-manager
+		// ca-injector, so it is retained rather than removed by the update
+		if cluster.caBundle == nil {
+			preserveWebhookCABundles(configuration, existing)
+		}
+
+		configuration.SetResourceVersion(existing.GetResourceVersion())
+		err = klient.Update(ctx, configuration)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to install webhook configuration %v. %w", configuration.GetName(), err)
+	}
+
+	return nil
+}
+
+// preserveWebhookCABundles sets the ca bundle of each webhook of the configuration that does not set one to that of the webhook of the same name
+// in the existing configuration
+func preserveWebhookCABundles(configuration, existing client.Object) {
+	switch configuration := configuration.(type) {
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		preserveCABundles(configuration.Webhooks, existing.(*admissionregistrationv1.ValidatingWebhookConfiguration).Webhooks, func(w *admissionregistrationv1.ValidatingWebhook) (string, *admissionregistrationv1.WebhookClientConfig) {
+			return w.Name, &w.ClientConfig
+		})
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		preserveCABundles(configuration.Webhooks, existing.(*admissionregistrationv1.MutatingWebhookConfiguration).Webhooks, func(w *admissionregistrationv1.MutatingWebhook) (string, *admissionregistrationv1.WebhookClientConfig) {
+			return w.Name, &w.ClientConfig
+		})
+	}
+}
+
+func preserveCABundles[T any](webhooks, existing []T, clientConfigOf func(*T) (string, *admissionregistrationv1.WebhookClientConfig)) {
+	caBundles := map[string][]byte{}
+
+	for i := range existing {
+		name, clientConfig := clientConfigOf(&existing[i])
+		caBundles[name] = clientConfig.CABundle
+	}
+
+	for i := range webhooks {
+		if name, clientConfig := clientConfigOf(&webhooks[i]); len(clientConfig.CABundle) == 0 {
+			clientConfig.CABundle = caBundles[name]
+		}
+	}
+}